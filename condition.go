@@ -0,0 +1,417 @@
+package numbersix
+
+import (
+	"strings"
+
+	"hawx.me/code/numbersix/internal/rdf"
+)
+
+// A Condition matches the subjects that a query should return triples for.
+// Conditions can be combined with And, Or and Not, for example to find posts
+// that are notes or photos that are not private:
+//
+//    And(
+//      Or(Eq("post-type", "note"), Eq("post-type", "photo")),
+//      Not(Eq("visibility", "private")),
+//    )
+type Condition interface {
+	// subjects returns the SQL to select the matching subjects, as a single
+	// column named subject. It must be a simple SELECT, so that it can be used
+	// as part of a compound SELECT.
+	subjects(t table) (string, []interface{})
+
+	// String returns the condition as it would be written for Parse.
+	String() string
+}
+
+// allSubjects returns the SQL to select every subject.
+func allSubjects(name string) string {
+	return "SELECT DISTINCT subject FROM " + name
+}
+
+// Eq is a condition that matches subjects having the predicate and value. When
+// the DB flattens objects, a predicate containing "." that is not an absolute
+// IRI is also treated as a path of predicates through referenced subjects, so
+// Eq("author.name", "John") will match subjects with an "author" that
+// references a subject with the "name" John.
+func Eq(predicate string, value interface{}) Condition {
+	v, _ := marshal(value)
+
+	return whereClause{predicate: predicate, value: v}
+}
+
+type whereClause struct{ predicate, value string }
+
+func (where whereClause) subjects(t table) (string, []interface{}) {
+	qs := "SELECT DISTINCT subject FROM " + t.name + " WHERE predicate = ? AND value = ?"
+	args := []interface{}{where.predicate, where.value}
+
+	if !t.flatten || rdf.IsIRI(where.predicate) {
+		return qs, args
+	}
+
+	path := strings.Split(where.predicate, ".")
+	if len(path) == 1 {
+		return qs, args
+	}
+
+	referenced := "SELECT '" + refPrefix + "' || subject FROM " + t.name + " WHERE predicate = ? AND value = ?"
+	pathArgs := []interface{}{path[len(path)-1], where.value}
+
+	for i := len(path) - 2; i > 0; i-- {
+		referenced = "SELECT '" + refPrefix + "' || subject FROM " + t.name + " WHERE predicate = ? AND value IN (" + referenced + ")"
+		pathArgs = append([]interface{}{path[i]}, pathArgs...)
+	}
+
+	qs = "SELECT DISTINCT subject FROM " + t.name +
+		" WHERE (predicate = ? AND value = ?) OR (predicate = ? AND value IN (" + referenced + "))"
+	args = append(args, path[0])
+
+	return qs, append(args, pathArgs...)
+}
+
+func (where whereClause) String() string {
+	return formatWord(where.predicate) + " = " + formatValue(where.value)
+}
+
+// in returns a condition matching subjects having the predicate and any of the
+// values.
+func in(predicate string, values []interface{}) Condition {
+	conditions := make([]Condition, len(values))
+	for i, value := range values {
+		conditions[i] = Eq(predicate, value)
+	}
+
+	return Or(conditions...)
+}
+
+// Prefix is a condition that matches subjects having the predicate with a value
+// that begins with the value given.
+func Prefix(predicate string, value interface{}) Condition {
+	v, _ := marshal(value)
+
+	return prefixCondition{predicate: predicate, value: v}
+}
+
+type prefixCondition struct{ predicate, value string }
+
+func (c prefixCondition) subjects(t table) (string, []interface{}) {
+	// strings are marshaled with quotes, so drop the closing quote to match
+	// longer strings
+	pattern := c.value
+	if strings.HasPrefix(pattern, `"`) {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	return "SELECT DISTINCT subject FROM " + t.name + " WHERE predicate = ? AND value LIKE ? ESCAPE '\\'",
+		[]interface{}{c.predicate, escapeLike(pattern) + "%"}
+}
+
+func (c prefixCondition) String() string {
+	return formatWord(c.predicate) + " ^= " + formatValue(c.value)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// Has is a condition that matches subjects having the predicate, with any value.
+func Has(predicate string) Condition {
+	return hasCondition(predicate)
+}
+
+type hasCondition string
+
+func (predicate hasCondition) subjects(t table) (string, []interface{}) {
+	return "SELECT DISTINCT subject FROM " + t.name + " WHERE predicate = ?", []interface{}{string(predicate)}
+}
+
+func (predicate hasCondition) String() string {
+	return "has " + formatWord(string(predicate))
+}
+
+// Range is a condition that matches subjects having the predicate with a value
+// between low and high, inclusive. Either bound may be nil to leave that end of
+// the range open.
+func Range(predicate string, low, high interface{}) Condition {
+	c := &rangeCondition{predicate: predicate, inclusive: true}
+	if low != nil {
+		c.lower = newBound(low)
+	}
+	if high != nil {
+		c.upper = newBound(high)
+	}
+
+	return c
+}
+
+type rangeCondition struct {
+	predicate    string
+	lower, upper *bound
+	inclusive    bool
+}
+
+func (c *rangeCondition) subjects(t table) (string, []interface{}) {
+	bounds, args := c.bounds()
+
+	return "SELECT DISTINCT subject FROM " + t.name + " WHERE predicate = ?" + bounds,
+		append([]interface{}{c.predicate}, args...)
+}
+
+// bounds returns the SQL to restrict values of the predicate to the range.
+func (c *rangeCondition) bounds() (qs string, args []interface{}) {
+	lowerOp, upperOp := ">", "<"
+	if c.inclusive {
+		lowerOp, upperOp = ">=", "<="
+	}
+
+	if c.lower != nil {
+		condition, conditionArgs := c.lower.condition(lowerOp)
+		qs += condition
+		args = append(args, conditionArgs...)
+	}
+
+	if c.upper != nil {
+		condition, conditionArgs := c.upper.condition(upperOp)
+		qs += condition
+		args = append(args, conditionArgs...)
+	}
+
+	return
+}
+
+func (c *rangeCondition) String() string {
+	predicate := formatWord(c.predicate)
+
+	if c.lower != nil && c.upper != nil && c.inclusive {
+		return predicate + " between " + formatValue(c.lower.value) + " and " + formatValue(c.upper.value)
+	}
+
+	lowerOp, upperOp := " > ", " < "
+	if c.inclusive {
+		lowerOp, upperOp = " >= ", " <= "
+	}
+
+	var parts []string
+	if c.lower != nil {
+		parts = append(parts, predicate+lowerOp+formatValue(c.lower.value))
+	}
+	if c.upper != nil {
+		parts = append(parts, predicate+upperOp+formatValue(c.upper.value))
+	}
+	if len(parts) == 0 {
+		return "has " + predicate
+	}
+
+	return strings.Join(parts, " and ")
+}
+
+// compound returns true if the range is written as more than one condition.
+func (c *rangeCondition) compound() bool {
+	return c.lower != nil && c.upper != nil && !c.inclusive
+}
+
+// bound is a value that a predicate's values must be above or below.
+type bound struct {
+	value  string
+	kind   string
+	native interface{}
+}
+
+func newBound(value interface{}) *bound {
+	v, _ := marshal(value)
+	kind, native := typed(v)
+
+	return &bound{value: v, kind: kind, native: native}
+}
+
+// condition returns the SQL comparing a value with the bound, using op.
+func (b *bound) condition(op string) (string, []interface{}) {
+	if b.native != nil {
+		return " AND value_type = ? AND typed_value " + op + " ?", []interface{}{b.kind, b.native}
+	}
+
+	return " AND value " + op + " ?", []interface{}{b.value}
+}
+
+// Not is a condition that matches subjects that do not match the condition.
+func Not(condition Condition) Condition {
+	return notCondition{condition}
+}
+
+type notCondition struct{ condition Condition }
+
+func (c notCondition) subjects(t table) (string, []interface{}) {
+	return andCondition{c}.subjects(t)
+}
+
+func (c notCondition) String() string {
+	switch condition := c.condition.(type) {
+	case hasCondition:
+		return "without " + formatWord(string(condition))
+	case whereClause:
+		return formatWord(condition.predicate) + " != " + formatValue(condition.value)
+	}
+
+	if isCompound(c.condition) {
+		return "not (" + c.condition.String() + ")"
+	}
+
+	return "not " + c.condition.String()
+}
+
+// And is a condition that matches subjects matching all of the conditions. With
+// no conditions it matches every subject.
+func And(conditions ...Condition) Condition {
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+
+	return andCondition(conditions)
+}
+
+type andCondition []Condition
+
+func (c andCondition) subjects(t table) (qs string, args []interface{}) {
+	var matches, excludes []Condition
+	for _, condition := range c {
+		if not, ok := condition.(notCondition); ok {
+			excludes = append(excludes, not.condition)
+		} else {
+			matches = append(matches, condition)
+		}
+	}
+
+	if len(matches) == 0 {
+		if len(excludes) == 0 {
+			return allSubjects(t.name), nil
+		}
+
+		qs = allSubjects(t.name)
+	}
+
+	for i, condition := range matches {
+		if i > 0 {
+			qs += " INTERSECT "
+		}
+		clause, clauseArgs := condition.subjects(t)
+		qs += clause
+		args = append(args, clauseArgs...)
+	}
+
+	for _, condition := range excludes {
+		clause, clauseArgs := condition.subjects(t)
+		qs += " EXCEPT " + clause
+		args = append(args, clauseArgs...)
+	}
+
+	return "SELECT subject FROM ( " + qs + " )", args
+}
+
+func (c andCondition) String() string {
+	if len(c) == 0 {
+		return "all"
+	}
+
+	parts := make([]string, len(c))
+	for i, condition := range c {
+		if or, ok := condition.(orCondition); ok && or.compound() {
+			parts[i] = "(" + or.String() + ")"
+		} else {
+			parts[i] = condition.String()
+		}
+	}
+
+	return strings.Join(parts, " and ")
+}
+
+// Or is a condition that matches subjects matching any of the conditions. With
+// no conditions it matches no subjects.
+func Or(conditions ...Condition) Condition {
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+
+	return orCondition(conditions)
+}
+
+type orCondition []Condition
+
+func (c orCondition) subjects(t table) (qs string, args []interface{}) {
+	if len(c) == 0 {
+		return "SELECT subject FROM " + t.name + " WHERE 0", nil
+	}
+
+	for i, condition := range c {
+		if i > 0 {
+			qs += " UNION "
+		}
+		clause, clauseArgs := condition.subjects(t)
+		qs += clause
+		args = append(args, clauseArgs...)
+	}
+
+	return "SELECT subject FROM ( " + qs + " )", args
+}
+
+func (c orCondition) String() string {
+	if len(c) == 0 {
+		return "none"
+	}
+
+	if predicate, ok := c.in(); ok {
+		values := make([]string, len(c))
+		for i, condition := range c {
+			values[i] = formatValue(condition.(whereClause).value)
+		}
+
+		return formatWord(predicate) + " in (" + strings.Join(values, ", ") + ")"
+	}
+
+	parts := make([]string, len(c))
+	for i, condition := range c {
+		parts[i] = condition.String()
+	}
+
+	return strings.Join(parts, " or ")
+}
+
+// in returns the predicate if each condition is a whereClause for it, so that
+// the condition can be written using "in".
+func (c orCondition) in() (string, bool) {
+	var predicate string
+
+	for i, condition := range c {
+		where, ok := condition.(whereClause)
+		if !ok || (i > 0 && where.predicate != predicate) {
+			return "", false
+		}
+		predicate = where.predicate
+	}
+
+	return predicate, true
+}
+
+// compound returns true if the condition is written with "or".
+func (c orCondition) compound() bool {
+	_, in := c.in()
+
+	return len(c) > 0 && !in
+}
+
+// isCompound returns true if the condition must be grouped in parentheses when
+// negated.
+func isCompound(condition Condition) bool {
+	switch c := condition.(type) {
+	case andCondition:
+		return len(c) > 0
+	case orCondition:
+		return c.compound()
+	case *rangeCondition:
+		return c.compound()
+	}
+
+	return false
+}
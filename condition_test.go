@@ -0,0 +1,223 @@
+package numbersix
+
+import (
+	"testing"
+
+	"hawx.me/code/assert"
+)
+
+func TestSelect(t *testing.T) {
+	db, _ := Open("file::memory:")
+
+	db.Set("1", "post-type", "note")
+	db.Set("1", "published", 3)
+	db.Set("1", "category", "go")
+	db.Set("2", "post-type", "photo")
+	db.Set("2", "published", 1)
+	db.Set("2", "visibility", "private")
+	db.Set("3", "post-type", "article")
+	db.Set("3", "published", 2)
+	db.Set("3", "category", "go")
+	db.Set("3", "category", "sql")
+	db.Set("4", "name", "100%_done")
+	db.Set("5", "name", "100 things")
+
+	t.Run("all", func(t *testing.T) {
+		triples, err := db.List(Select().Limit(2))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"1", "category"},
+			{"1", "post-type"},
+			{"1", "published"},
+			{"2", "post-type"},
+			{"2", "published"},
+			{"2", "visibility"},
+		})
+	})
+
+	t.Run("Eq", func(t *testing.T) {
+		triples, err := db.List(Select(Eq("category", "go")))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"1", "category"},
+			{"1", "post-type"},
+			{"1", "published"},
+			{"3", "category"},
+			{"3", "category"},
+			{"3", "post-type"},
+			{"3", "published"},
+		})
+	})
+
+	t.Run("Prefix", func(t *testing.T) {
+		triples, err := db.List(Select(Prefix("name", "100%")))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"4", "name"},
+		})
+	})
+
+	t.Run("Has and Not", func(t *testing.T) {
+		triples, err := db.List(Select(Has("post-type"), Not(Has("category"))))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"2", "post-type"},
+			{"2", "published"},
+			{"2", "visibility"},
+		})
+	})
+
+	t.Run("Not alone", func(t *testing.T) {
+		triples, err := db.List(Select(Not(Has("published"))))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"4", "name"},
+			{"5", "name"},
+		})
+	})
+
+	t.Run("Or with And", func(t *testing.T) {
+		triples, err := db.List(Select(Or(
+			And(Eq("category", "go"), Eq("category", "sql")),
+			Eq("post-type", "photo"),
+		)))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"2", "post-type"},
+			{"2", "published"},
+			{"2", "visibility"},
+			{"3", "category"},
+			{"3", "category"},
+			{"3", "post-type"},
+			{"3", "published"},
+		})
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		triples, err := db.List(Select(Range("published", 2, nil)).Ascending("published"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"3", "category"},
+			{"3", "category"},
+			{"3", "post-type"},
+			{"3", "published"},
+			{"1", "category"},
+			{"1", "post-type"},
+			{"1", "published"},
+		})
+	})
+
+	t.Run("Descending with Limit", func(t *testing.T) {
+		triples, err := db.List(Select(Not(Eq("visibility", "private"))).Descending("published").Limit(1))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"1", "category"},
+			{"1", "post-type"},
+			{"1", "published"},
+		})
+	})
+
+	t.Run("ordered by multiple values", func(t *testing.T) {
+		triples, err := db.List(Select().Descending("category"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"3", "category"},
+			{"3", "category"},
+			{"3", "post-type"},
+			{"3", "published"},
+			{"1", "category"},
+			{"1", "post-type"},
+			{"1", "published"},
+		})
+	})
+
+	t.Run("pages", func(t *testing.T) {
+		query := Select(Has("post-type")).Limit(2)
+
+		triples, token, err := db.ListPage(query)
+		assert.Nil(t, err)
+		assert.Len(t, triples, 6)
+		assert.NotNil(t, token)
+
+		triples, token, err = db.ListPage(query.Continue(token))
+		assert.Nil(t, err)
+		assert.Nil(t, token)
+
+		assertTriples(t, triples, []pair{
+			{"3", "category"},
+			{"3", "category"},
+			{"3", "post-type"},
+			{"3", "published"},
+		})
+	})
+
+	t.Run("Any", func(t *testing.T) {
+		ok, err := db.Any(Select(Eq("post-type", "photo"), Has("category")))
+		assert.Nil(t, err)
+		assert.False(t, ok)
+
+		ok, err = db.Any(Select(Eq("post-type", "photo"), Has("visibility")))
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	})
+}
+
+func TestQueryModifiers(t *testing.T) {
+	db, _ := Open("file::memory:")
+
+	db.Set("a", "name", "Jane")
+	db.Set("a", "age", 23)
+	db.Set("b", "name", "John")
+	db.Set("b", "age", 25)
+	db.Set("b", "deleted", true)
+	db.Set("c", "name", "Jean")
+	db.Set("c", "age", 24)
+
+	t.Run("Begins with Where", func(t *testing.T) {
+		triples, err := db.List(Begins("name", "J").Where("age", 24))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"c", "age"},
+			{"c", "name"},
+		})
+	})
+
+	t.Run("Ascending with Without", func(t *testing.T) {
+		triples, err := db.List(Ascending("age").Without("deleted"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"a", "age"},
+			{"a", "name"},
+			{"c", "age"},
+			{"c", "name"},
+		})
+	})
+
+	t.Run("After with Has", func(t *testing.T) {
+		triples, err := db.List(After("age", 23).Has("deleted"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"b", "age"},
+			{"b", "deleted"},
+			{"b", "name"},
+		})
+	})
+
+	t.Run("About with Without", func(t *testing.T) {
+		triples, err := db.List(About("b").Without("deleted"))
+		assert.Nil(t, err)
+		assert.Len(t, triples, 0)
+	})
+}
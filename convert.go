@@ -2,14 +2,113 @@ package numbersix
 
 import (
 	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"hawx.me/code/numbersix/internal/schema"
+)
+
+// Types of value that can be stored, as recorded in the value_type column.
+const (
+	typeString = schema.TypeString
+	typeNumber = schema.TypeNumber
+	typeTime   = schema.TypeTime
+	typeBool   = schema.TypeBool
+	typeNull   = schema.TypeNull
+	typeArray  = schema.TypeArray
+	typeObject = schema.TypeObject
+	typeRef    = schema.TypeRef
 )
 
+// refPrefix starts the marshaled form of a Ref. As it can never start valid
+// JSON a Ref will not be confused with any other value.
+const refPrefix = schema.RefPrefix
+
+// Ref is a value that references another subject. It is stored distinctly from
+// a string, so
+//
+//    db.Set("post", "author", Ref("john"))
+//
+// can be found using Where("author", Ref("john")) but not Where("author",
+// "john"). When read with Triple.Value a Ref can be decoded into a string, or
+// into an empty interface as a Ref.
+type Ref string
+
 func marshal(v interface{}) (string, error) {
+	if r, ok := v.(Ref); ok {
+		return refPrefix + string(r), nil
+	}
+
 	m, err := json.Marshal(v)
 
 	return string(m), err
 }
 
 func unmarshal(data string, v interface{}) error {
+	if strings.HasPrefix(data, refPrefix) {
+		return unmarshalRef(Ref(strings.TrimPrefix(data, refPrefix)), v)
+	}
+
 	return json.Unmarshal([]byte(data), &v)
 }
+
+// unmarshalRef sets the value pointed to by v to r. If v points to an empty
+// interface it is set to r, if it points to a string it is set to the subject
+// referenced.
+func unmarshalRef(r Ref, v interface{}) error {
+	rv := reflect.ValueOf(v)
+
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		elem := rv.Elem()
+		if elem.Kind() == reflect.Interface && !elem.IsNil() && elem.Elem().Kind() == reflect.Ptr {
+			rv = elem.Elem()
+			continue
+		}
+
+		switch {
+		case elem.Kind() == reflect.Interface && elem.NumMethod() == 0:
+			elem.Set(reflect.ValueOf(r))
+			return nil
+		case elem.Kind() == reflect.String:
+			elem.SetString(string(r))
+			return nil
+		}
+		break
+	}
+
+	return errors.New("cannot unmarshal reference into " + rv.Type().String())
+}
+
+// canonical returns v, decoded from JSON using json.Number, with each number
+// replaced by the value returned by number, so that it marshals the same as it
+// would have if given to Set.
+func canonical(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := number(string(v)); err == nil {
+			return n
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = canonical(v[i])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = canonical(v[key])
+		}
+	}
+
+	return v
+}
+
+// number parses s as a number, as described by schema.Number.
+func number(s string) (interface{}, error) {
+	return schema.Number(s)
+}
+
+// typed returns the type of the marshaled data, and for numbers and times the
+// native value stored in the typed_value column, as described by schema.Typed.
+func typed(data string) (kind string, native interface{}) {
+	return schema.Typed(data)
+}
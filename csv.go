@@ -0,0 +1,238 @@
+package numbersix
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A CSVOption changes how ExportCSV and ImportCSV read or write values.
+type CSVOption func(*csvOptions)
+
+type csvOptions struct {
+	separator string
+}
+
+// Separator sets the string that the values of a predicate with more than one
+// value are joined with, and split by. It defaults to ";". Where the separator,
+// or a backslash, appears in a value it is escaped with a backslash. If the
+// separator is empty cells are not split, and only one value can be written for
+// each predicate. The separator cannot contain a backslash.
+func Separator(separator string) CSVOption {
+	return func(o *csvOptions) {
+		o.separator = separator
+	}
+}
+
+func newCSVOptions(opts []CSVOption) (csvOptions, error) {
+	options := csvOptions{separator: ";"}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if strings.Contains(options.separator, `\`) {
+		return options, errors.New(`separator cannot contain "\"`)
+	}
+
+	return options, nil
+}
+
+// join returns the values joined by the separator, escaping the separator and
+// backslashes within them.
+func (o csvOptions) join(values []string) string {
+	if o.separator != "" {
+		escaper := strings.NewReplacer(`\`, `\\`, o.separator, `\`+o.separator)
+		for i, value := range values {
+			values[i] = escaper.Replace(value)
+		}
+	}
+
+	return strings.Join(values, o.separator)
+}
+
+// split returns the values in the cell, the reverse of join.
+func (o csvOptions) split(cell string) []string {
+	if o.separator == "" {
+		return []string{cell}
+	}
+
+	var values []string
+	var b strings.Builder
+
+	for i := 0; i < len(cell); {
+		switch {
+		case cell[i] == '\\' && strings.HasPrefix(cell[i+1:], o.separator):
+			b.WriteString(o.separator)
+			i += 1 + len(o.separator)
+
+		case cell[i] == '\\' && i+1 < len(cell):
+			b.WriteByte(cell[i+1])
+			i += 2
+
+		case strings.HasPrefix(cell[i:], o.separator):
+			values = append(values, b.String())
+			b.Reset()
+			i += len(o.separator)
+
+		default:
+			b.WriteByte(cell[i])
+			i++
+		}
+	}
+
+	return append(values, b.String())
+}
+
+// ExportCSV writes the groups to w as CSV, with a row for each group. The first
+// column is the subject, with the header "subject", followed by a column for
+// each predicate in columns. If columns is empty there is a column for each
+// predicate of the groups, in order of name.
+//
+// Strings are written as they are, a Ref as the subject it references, and any
+// other value as JSON. A predicate with more than one value has its values
+// joined by the separator. As the first column is "subject" there cannot be a
+// column for a predicate of the same name.
+func ExportCSV(w io.Writer, groups []Group, columns []string, opts ...CSVOption) error {
+	options, err := newCSVOptions(opts)
+	if err != nil {
+		return err
+	}
+
+	if len(columns) == 0 {
+		seen := map[string]bool{}
+		for _, group := range groups {
+			for predicate := range group.Properties {
+				if !seen[predicate] {
+					seen[predicate] = true
+					columns = append(columns, predicate)
+				}
+			}
+		}
+		sort.Strings(columns)
+	}
+
+	for _, column := range columns {
+		if column == "subject" {
+			return errors.New(`predicate "subject" cannot be written as a column`)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(append([]string{"subject"}, columns...)); err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		row := make([]string, len(columns)+1)
+		row[0] = group.Subject
+
+		for i, predicate := range columns {
+			if options.separator == "" && len(group.Properties[predicate]) > 1 {
+				return errors.New(strconv.Quote(group.Subject) + " has more than one value for " +
+					strconv.Quote(predicate) + " but there is no separator")
+			}
+
+			values := make([]string, len(group.Properties[predicate]))
+			for j, value := range group.Properties[predicate] {
+				s, err := csvValue(value)
+				if err != nil {
+					return err
+				}
+				values[j] = s
+			}
+			row[i+1] = options.join(values)
+		}
+
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case Ref:
+		return string(v), nil
+	}
+
+	return marshal(value)
+}
+
+// ImportCSV reads CSV from r, as written by ExportCSV, returning a group for
+// each row. The first row is the header, and the column with the header subject
+// gives the subject of each row, it must appear only once; the other columns are
+// the predicates. Each cell is split by the separator into the values of its
+// predicate, and empty cells have no values.
+//
+// All values are read as strings, as CSV does not record their type. The groups
+// can be stored with SetProperties.
+func ImportCSV(r io.Reader, subject string, opts ...CSVOption) ([]Group, error) {
+	options, err := newCSVOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cr := csv.NewReader(r)
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("missing header")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	subjectColumn := -1
+	for i, column := range header {
+		if column == subject {
+			if subjectColumn >= 0 {
+				return nil, errors.New("duplicate subject column " + strconv.Quote(subject))
+			}
+			subjectColumn = i
+		}
+	}
+	if subjectColumn < 0 {
+		return nil, errors.New("missing subject column " + strconv.Quote(subject))
+	}
+
+	var groups []Group
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return groups, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if record[subjectColumn] == "" {
+			return nil, errors.New("row " + strconv.Itoa(row) + " has no subject")
+		}
+
+		group := Group{
+			Subject:    record[subjectColumn],
+			Properties: map[string][]interface{}{},
+		}
+
+		for i, cell := range record {
+			if i == subjectColumn || cell == "" {
+				continue
+			}
+
+			for _, value := range options.split(cell) {
+				group.Properties[header[i]] = append(group.Properties[header[i]], value)
+			}
+		}
+
+		groups = append(groups, group)
+	}
+}
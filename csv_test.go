@@ -0,0 +1,150 @@
+package numbersix
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"hawx.me/code/assert"
+)
+
+func TestExportCSV(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	db.Set("post/1", "name", "Hello, world")
+	db.Set("post/1", "category", "go", "sqlite")
+	db.Set("post/1", "author", Ref("john"))
+	db.Set("post/1", "likes", 5)
+	db.Set("post/2", "name", "Goodbye")
+	db.Set("post/2", "draft", true)
+
+	groups, _ := db.Groups(All())
+
+	var buf bytes.Buffer
+	assert.Nil(ExportCSV(&buf, groups, []string{"name", "category", "likes"}))
+	assert.Equal(`subject,name,category,likes
+post/1,"Hello, world",go;sqlite,5
+post/2,Goodbye,,
+`, buf.String())
+
+	buf.Reset()
+	assert.Nil(ExportCSV(&buf, groups, nil, Separator(" | ")))
+	assert.Equal(`subject,author,category,draft,likes,name
+post/1,john,go | sqlite,,5,"Hello, world"
+post/2,,,true,,Goodbye
+`, buf.String())
+}
+
+func TestImportCSV(t *testing.T) {
+	assert := assert.New(t)
+
+	groups, err := ImportCSV(strings.NewReader(`name,url,category
+Hello,https://example.com/1,go|sqlite
+Goodbye,https://example.com/2,
+`), "url", Separator("|"))
+	assert.Nil(err)
+
+	assert.Equal([]Group{{
+		Subject: "https://example.com/1",
+		Properties: map[string][]interface{}{
+			"name":     {"Hello"},
+			"category": {"go", "sqlite"},
+		},
+	}, {
+		Subject: "https://example.com/2",
+		Properties: map[string][]interface{}{
+			"name": {"Goodbye"},
+		},
+	}}, groups)
+
+	t.Run("round trip", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Nil(ExportCSV(&buf, groups, nil))
+
+		read, err := ImportCSV(&buf, "subject")
+		assert.Nil(err)
+		assert.Equal(groups, read)
+	})
+}
+
+func TestCSVSeparatorInValues(t *testing.T) {
+	assert := assert.New(t)
+
+	groups := []Group{{
+		Subject: "post/1",
+		Properties: map[string][]interface{}{
+			"category": {"a;b", `c\d`, `e\;f`},
+			"name":     {"Hello; world"},
+		},
+	}}
+
+	var buf bytes.Buffer
+	assert.Nil(ExportCSV(&buf, groups, nil))
+	assert.Equal(`subject,category,name
+post/1,a\;b;c\\d;e\\\;f,Hello\; world
+`, buf.String())
+
+	read, err := ImportCSV(&buf, "subject")
+	assert.Nil(err)
+	assert.Equal(groups, read)
+
+	t.Run("without separator", func(t *testing.T) {
+		buf.Reset()
+		err := ExportCSV(&buf, groups, []string{"name"}, Separator(""))
+		assert.Nil(err)
+		assert.Equal("subject,name\npost/1,Hello; world\n", buf.String())
+
+		read, err := ImportCSV(&buf, "subject", Separator(""))
+		assert.Nil(err)
+		assert.Equal([]Group{{
+			Subject:    "post/1",
+			Properties: map[string][]interface{}{"name": {"Hello; world"}},
+		}}, read)
+
+		err = ExportCSV(&buf, groups, nil, Separator(""))
+		if assert.NotNil(err) {
+			assert.Equal(`"post/1" has more than one value for "category" but there is no separator`, err.Error())
+		}
+	})
+}
+
+func TestExportCSVErrors(t *testing.T) {
+	groups := []Group{{
+		Subject:    "post/1",
+		Properties: map[string][]interface{}{"subject": {"Hello"}},
+	}}
+
+	err := ExportCSV(&bytes.Buffer{}, groups, nil)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, `predicate "subject" cannot be written as a column`, err.Error())
+	}
+
+	err = ExportCSV(&bytes.Buffer{}, groups, []string{"name"}, Separator(`\`))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, `separator cannot contain "\"`, err.Error())
+	}
+}
+
+func TestImportCSVErrors(t *testing.T) {
+	testCases := map[string]string{
+		"":                         "missing header",
+		"name,url\nHello,a:b\n":    `missing subject column "subject"`,
+		"subject,name\n,Hello\n":   "row 2 has no subject",
+		"subject,name\na,b,c\n":    "record on line 2: wrong number of fields",
+		"subject,subject\na,b\n":   `duplicate subject column "subject"`,
+		"subject,name\na:b,Hi\n":   "",
+		"subject,name\na:b,Hi\r\n": "",
+	}
+
+	for document, expected := range testCases {
+		t.Run(document, func(t *testing.T) {
+			_, err := ImportCSV(strings.NewReader(document), "subject")
+			if expected == "" {
+				assert.Nil(t, err)
+			} else if assert.NotNil(t, err) {
+				assert.Equal(t, expected, err.Error())
+			}
+		})
+	}
+}
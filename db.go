@@ -1,16 +1,21 @@
 package numbersix
 
 import (
+	"context"
 	"database/sql"
 
+	"hawx.me/code/numbersix/internal/schema"
+
 	// register sqlite3 for database/sql
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // DB stores triples.
 type DB struct {
-	db   *sql.DB
-	name string
+	db      *sql.DB
+	name    string
+	ctx     context.Context
+	flatten bool
 }
 
 // Open returns a new triple store DB writing to a sqlite database at the path
@@ -34,15 +39,141 @@ func (d *DB) Close() error {
 	return d.db.Close()
 }
 
+// WithContext returns a copy of the DB that uses ctx for all operations, so
+// that they are cancelled when ctx is done. The copy shares the underlying
+// sqlite database, so closing either will close both.
+func (d *DB) WithContext(ctx context.Context) *DB {
+	c := *d
+	c.ctx = ctx
+	return &c
+}
+
+func (d *DB) context() context.Context {
+	if d.ctx == nil {
+		return context.Background()
+	}
+
+	return d.ctx
+}
+
+func init() {
+	schema.TableOf = func(db interface{}) schema.Table {
+		d := db.(*DB)
+
+		return schema.Table{
+			Name: d.name,
+			Query: func(query string, args ...interface{}) (*sql.Rows, error) {
+				return d.db.QueryContext(d.context(), query, args...)
+			},
+		}
+	}
+}
+
+func (d *DB) store() store {
+	return store{ctx: d.context(), q: d.db, name: d.name, flatten: d.flatten}
+}
+
 func migrate(db *sql.DB, name string) error {
 	_, err := db.Exec(`
     CREATE TABLE IF NOT EXISTS ` + name + ` (
-      subject   TEXT,
-      predicate TEXT,
-      value     TEXT,
+      subject     TEXT,
+      predicate   TEXT,
+      value       TEXT,
+      value_type  TEXT,
+      typed_value,
       PRIMARY KEY (subject, predicate, value)
     );
   `)
+	if err != nil {
+		return err
+	}
+
+	return migrateTyped(db, name)
+}
+
+// migrateTyped adds the value_type and typed_value columns to tables created
+// before they existed, and populates them for the existing values.
+func migrateTyped(db *sql.DB, name string) error {
+	rows, err := db.Query("PRAGMA table_info(" + name + ")")
+	if err != nil {
+		return err
+	}
+
+	hasTyped := false
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			column, kind     string
+			dflt             interface{}
+		)
+		if err = rows.Scan(&cid, &column, &kind, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		if column == "value_type" {
+			hasTyped = true
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil || hasTyped {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err = backfillTyped(tx, name); err != nil {
+		terr := tx.Rollback()
+		if terr != nil {
+			return terr
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func backfillTyped(tx *sql.Tx, name string) error {
+	if _, err := tx.Exec("ALTER TABLE " + name + " ADD COLUMN value_type TEXT"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("ALTER TABLE " + name + " ADD COLUMN typed_value"); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT DISTINCT value FROM " + name)
+	if err != nil {
+		return err
+	}
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			rows.Close()
+			return err
+		}
+		values = append(values, value)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare("UPDATE " + name + " SET value_type = ?, typed_value = ? WHERE value = ?")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, value := range values {
+		kind, native := typed(value)
+		if _, err = stmt.Exec(kind, native, value); err != nil {
+			return err
+		}
+	}
 
-	return err
+	return nil
 }
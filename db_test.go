@@ -1,6 +1,7 @@
 package numbersix
 
 import (
+	"context"
 	"database/sql"
 	"testing"
 	"time"
@@ -152,3 +153,66 @@ func TestPossibleUsage(t *testing.T) {
 	assert.Equal("My third post", third.Title)
 	assert.Equal(time.Date(2019, time.March, 1, 12, 0, 0, 0, time.UTC), third.CreatedAt)
 }
+
+func TestMigrateTyped(t *testing.T) {
+	assert := assert.New(t)
+
+	sqlite, _ := sql.Open("sqlite3", "file::memory:")
+
+	_, err := sqlite.Exec(`
+    CREATE TABLE old (
+      subject   TEXT,
+      predicate TEXT,
+      value     TEXT,
+      PRIMARY KEY (subject, predicate, value)
+    );
+    INSERT INTO old(subject, predicate, value) VALUES ('a', 'age', '9'), ('b', 'age', '10'), ('c', 'age', '"11"');
+  `)
+	assert.Nil(err)
+
+	db, err := For(sqlite, "old")
+	assert.Nil(err)
+
+	triples, err := db.List(After("age", 9))
+	assert.Nil(err)
+	assertTriples(t, triples, []pair{
+		{"b", "age"},
+	})
+
+	// migrating again should do nothing
+	_, err = For(sqlite, "old")
+	assert.Nil(err)
+}
+
+func TestWithContext(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	assert.Nil(db.WithContext(context.Background()).Set("a", "name", "John"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cancelled := db.WithContext(ctx)
+
+	assert.Equal(context.Canceled, cancelled.Set("a", "name", "Jane"))
+	assert.Equal(context.Canceled, cancelled.DeleteSubject("a"))
+
+	_, err := cancelled.List(All())
+	assert.Equal(context.Canceled, err)
+
+	_, err = cancelled.Any(About("a"))
+	assert.Equal(context.Canceled, err)
+
+	err = cancelled.Update(func(tx *Tx) error {
+		return tx.Set("a", "name", "Jane")
+	})
+	assert.Equal(context.Canceled, err)
+
+	triples, err := db.List(All())
+	assert.Nil(err)
+	if assert.Len(triples, 1) {
+		var name string
+		assert.Nil(triples[0].Value(&name))
+		assert.Equal("John", name)
+	}
+}
@@ -3,12 +3,36 @@ package numbersix
 // DeleteValue removes the triple for the (subject, predicate, value) given. If
 // none exist, then this does nothing.
 func (d *DB) DeleteValue(subject, predicate string, value interface{}) error {
+	return d.store().deleteValue(subject, predicate, value)
+}
+
+// DeletePredicate removes all triples with the subject and predicate given. If
+// none exist, then this does nothing.
+func (d *DB) DeletePredicate(subject, predicate string) error {
+	return d.store().deletePredicate(subject, predicate)
+}
+
+// DeleteSubject removes all triples for the subject given. If none exist, then
+// this does nothing.
+func (d *DB) DeleteSubject(subject string) error {
+	return d.store().deleteSubject(subject)
+}
+
+func (s store) deleteValue(subject, predicate string, value interface{}) error {
 	v, err := marshal(value)
 	if err != nil {
 		return err
 	}
 
-	_, err = d.db.Exec("DELETE FROM "+d.name+" WHERE subject = ? AND predicate = ? AND value = ?",
+	if kind, _ := typed(v); s.flatten && kind == typeObject {
+		child := childSubject(subject, predicate, v)
+		if err := s.deleteChild(child); err != nil {
+			return err
+		}
+		v, _ = marshal(Ref(child))
+	}
+
+	_, err = s.q.ExecContext(s.ctx, "DELETE FROM "+s.name+" WHERE subject = ? AND predicate = ? AND value = ?",
 		subject,
 		predicate,
 		v)
@@ -16,20 +40,28 @@ func (d *DB) DeleteValue(subject, predicate string, value interface{}) error {
 	return err
 }
 
-// DeletePredicate removes all triples with the subject and predicate given. If
-// none exist, then this does nothing.
-func (d *DB) DeletePredicate(subject, predicate string) error {
-	_, err := d.db.Exec("DELETE FROM "+d.name+" WHERE subject = ? AND predicate = ?",
+func (s store) deletePredicate(subject, predicate string) error {
+	if s.flatten {
+		if err := s.deleteChildren(subject, predicate); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.q.ExecContext(s.ctx, "DELETE FROM "+s.name+" WHERE subject = ? AND predicate = ?",
 		subject,
 		predicate)
 
 	return err
 }
 
-// DeleteSubject removes all triples for the subject given. If none exist, then
-// this does nothing.
-func (d *DB) DeleteSubject(subject string) error {
-	_, err := d.db.Exec("DELETE FROM "+d.name+" WHERE subject = ?",
+func (s store) deleteSubject(subject string) error {
+	if s.flatten {
+		if err := s.deleteChildren(subject, ""); err != nil {
+			return err
+		}
+	}
+
+	_, err := s.q.ExecContext(s.ctx, "DELETE FROM "+s.name+" WHERE subject = ?",
 		subject)
 
 	return err
@@ -0,0 +1,259 @@
+package numbersix
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// dumpFormat and dumpVersion are written in the header of a dump, so that
+// Restore can tell whether it is able to read it.
+const (
+	dumpFormat  = "numbersix"
+	dumpVersion = 1
+)
+
+type dumpHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+}
+
+// dumpTriple is a triple as written in a dump. Value is the value as stored,
+// unless it is a Ref in which case Ref is the subject referenced.
+type dumpTriple struct {
+	Subject   string          `json:"subject"`
+	Predicate string          `json:"predicate"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	Ref       *string         `json:"ref,omitempty"`
+}
+
+// Dump writes every triple to w in the JSON Lines format, so that it can be
+// read by Restore. The first line is a header recording the format version,
+// followed by a line for each triple. For example:
+//
+//    {"format":"numbersix","version":1}
+//    {"subject":"post","predicate":"name","value":"Hello"}
+//    {"subject":"post","predicate":"author","ref":"john"}
+//
+// Triples are read from the database as they are written, so the dump does not
+// need to fit in memory.
+func (d *DB) Dump(w io.Writer) error {
+	return d.store().dump(w)
+}
+
+// A RestoreMode says what Restore does with the triples already stored.
+type RestoreMode int
+
+const (
+	// RestoreMerge adds the triples from the dump to those already stored.
+	RestoreMerge RestoreMode = iota
+
+	// RestoreReplace removes all triples already stored before adding those from
+	// the dump.
+	RestoreReplace
+)
+
+// RestoreOptions change how Restore reads a dump.
+type RestoreOptions struct {
+	// Mode is either RestoreMerge or RestoreReplace, it defaults to
+	// RestoreMerge.
+	Mode RestoreMode
+
+	// BatchSize is the number of triples stored in each transaction when
+	// merging, it defaults to 1000.
+	BatchSize int
+}
+
+// Restore reads a dump, written by Dump, from r and stores its triples. When
+// merging they are stored in batches, each in its own transaction, so if an
+// error is returned the triples read before it may have been stored. When
+// replacing the whole dump is restored in a single transaction, so that the
+// existing triples are kept if an error is returned.
+func (d *DB) Restore(r io.Reader, opts RestoreOptions) error {
+	if opts.Mode == RestoreReplace {
+		return d.Update(func(tx *Tx) error {
+			return tx.Restore(r, opts)
+		})
+	}
+
+	dr, err := newDumpReader(r)
+	if err != nil {
+		return err
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = importBatchSize
+	}
+
+	for done := false; !done; {
+		err := d.Update(func(tx *Tx) error {
+			s := tx.store()
+
+			for i := 0; i < batchSize; i++ {
+				ok, err := s.restoreTriple(dr)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					done = true
+					return nil
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s store) dump(w io.Writer) error {
+	rows, err := s.q.QueryContext(s.ctx, "SELECT subject, predicate, value FROM "+s.name+" ORDER BY subject, predicate, value")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(dumpHeader{Format: dumpFormat, Version: dumpVersion}); err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var record dumpTriple
+		var v string
+		if err := rows.Scan(&record.Subject, &record.Predicate, &v); err != nil {
+			return err
+		}
+
+		if kind, _ := typed(v); kind == typeRef {
+			ref := v[len(refPrefix):]
+			record.Ref = &ref
+		} else {
+			record.Value = json.RawMessage(v)
+		}
+
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+func (s store) restore(r io.Reader, mode RestoreMode) error {
+	dr, err := newDumpReader(r)
+	if err != nil {
+		return err
+	}
+
+	if mode == RestoreReplace {
+		if err := s.deleteAll(); err != nil {
+			return err
+		}
+	}
+
+	for {
+		ok, err := s.restoreTriple(dr)
+		if err != nil || !ok {
+			return err
+		}
+	}
+}
+
+// dumpReader reads the triples of a dump, after checking its header.
+type dumpReader struct {
+	dec     *json.Decoder
+	records int
+}
+
+func newDumpReader(r io.Reader) (*dumpReader, error) {
+	dec := json.NewDecoder(r)
+
+	var header dumpHeader
+	if err := dec.Decode(&header); err != nil {
+		if err == io.EOF {
+			return nil, errors.New("dump is empty")
+		}
+		return nil, err
+	}
+
+	if header.Format != dumpFormat {
+		return nil, errors.New("not a numbersix dump")
+	}
+	if header.Version != dumpVersion {
+		return nil, errors.New("unsupported dump version " + strconv.Itoa(header.Version))
+	}
+
+	return &dumpReader{dec: dec}, nil
+}
+
+// next returns the next triple with its value marshaled, or io.EOF when there
+// are no more triples.
+func (dr *dumpReader) next() (record dumpTriple, v string, err error) {
+	if err = dr.dec.Decode(&record); err != nil {
+		return
+	}
+	dr.records++
+
+	switch {
+	case record.Ref != nil:
+		v = refPrefix + *record.Ref
+
+	case len(record.Value) > 0:
+		var buf bytes.Buffer
+		if err = json.Compact(&buf, record.Value); err != nil {
+			return
+		}
+		v = buf.String()
+
+	default:
+		err = errors.New("record " + strconv.Itoa(dr.records) + " has no value")
+	}
+
+	return
+}
+
+// restoreTriple reads the next triple from the dump and stores it, returning
+// false when there are no more triples. The triple is stored as it was, even if
+// the DB flattens values.
+func (s store) restoreTriple(dr *dumpReader) (bool, error) {
+	record, v, err := dr.next()
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	kind, native := typed(v)
+	_, err = s.q.ExecContext(s.ctx, insertQuery(s.name),
+		record.Subject,
+		record.Predicate,
+		v,
+		kind,
+		native)
+
+	return true, err
+}
+
+func (s store) deleteAll() error {
+	_, err := s.q.ExecContext(s.ctx, "DELETE FROM "+s.name)
+
+	return err
+}
@@ -0,0 +1,126 @@
+package numbersix
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"hawx.me/code/assert"
+)
+
+func TestDump(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	db.Set("post", "name", "Hello <b>world</b>")
+	db.Set("post", "author", Ref("john"))
+	db.Set("post", "likes", 5)
+	db.Set("post", "content", map[string]string{"html": "<p>Hi</p>"})
+
+	var buf bytes.Buffer
+	assert.Nil(db.Dump(&buf))
+
+	assert.Equal(`{"format":"numbersix","version":1}
+{"subject":"post","predicate":"author","ref":"john"}
+{"subject":"post","predicate":"content","value":{"html":"\u003cp\u003eHi\u003c/p\u003e"}}
+{"subject":"post","predicate":"likes","value":5}
+{"subject":"post","predicate":"name","value":"Hello \u003cb\u003eworld\u003c/b\u003e"}
+`, buf.String())
+
+	t.Run("restore", func(t *testing.T) {
+		other, _ := Open("file::memory:")
+		assert.Nil(other.Restore(bytes.NewReader(buf.Bytes()), RestoreOptions{BatchSize: 3}))
+
+		expected, _ := db.List(All())
+		actual, _ := other.List(All())
+		assert.Equal(expected, actual)
+
+		ok, _ := other.Any(Where("author", Ref("john")))
+		assert.True(ok)
+		ok, _ = other.Any(After("likes", 4))
+		assert.True(ok)
+	})
+}
+
+func TestRestore(t *testing.T) {
+	const dump = `{"format":"numbersix","version":1}
+{"subject":"post","predicate":"name","value":"Hello"}
+{"subject":"post","predicate":"data","value":{ "a": [1, 2] }}
+`
+
+	t.Run("merge", func(t *testing.T) {
+		db, _ := Open("file::memory:")
+		db.Set("other", "name", "Other")
+		db.Set("post", "name", "Hello")
+
+		assert.Nil(t, db.Restore(strings.NewReader(dump), RestoreOptions{}))
+
+		triples, _ := db.List(All())
+		assert.Len(t, triples, 3)
+
+		ok, _ := db.Any(Where("data", map[string][]int{"a": {1, 2}}))
+		assert.True(t, ok)
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		db, _ := Open("file::memory:")
+		db.Set("other", "name", "Other")
+
+		assert.Nil(t, db.Restore(strings.NewReader(dump), RestoreOptions{Mode: RestoreReplace}))
+
+		triples, _ := db.List(All())
+		assert.Len(t, triples, 2)
+
+		ok, _ := db.Any(About("other"))
+		assert.False(t, ok)
+	})
+
+	t.Run("replace with error", func(t *testing.T) {
+		db, _ := Open("file::memory:")
+		db.Set("other", "name", "Other")
+
+		err := db.Restore(strings.NewReader(dump+`{"subject":"post","predicate":"bad"}`+"\n"), RestoreOptions{Mode: RestoreReplace, BatchSize: 1})
+		if assert.NotNil(t, err) {
+			assert.Equal(t, "record 3 has no value", err.Error())
+		}
+
+		triples, _ := db.List(All())
+		assert.Len(t, triples, 1)
+
+		ok, _ := db.Any(About("other"))
+		assert.True(t, ok)
+	})
+
+	t.Run("in transaction", func(t *testing.T) {
+		db, _ := Open("file::memory:")
+		db.Set("other", "name", "Other")
+
+		err := db.Update(func(tx *Tx) error {
+			return tx.Restore(strings.NewReader(dump+`{"subject":"post","predicate":"bad"}`+"\n"), RestoreOptions{Mode: RestoreReplace})
+		})
+		if assert.NotNil(t, err) {
+			assert.Equal(t, "record 3 has no value", err.Error())
+		}
+
+		triples, _ := db.List(All())
+		assert.Len(t, triples, 1)
+	})
+
+	errorCases := map[string]string{
+		"":                                     "dump is empty",
+		`{"format":"other","version":1}`:       "not a numbersix dump",
+		`{"format":"numbersix","version":2}`:   "unsupported dump version 2",
+		`{"format":"numbersix","version":1} {`: "unexpected EOF",
+	}
+
+	for document, expected := range errorCases {
+		t.Run(expected, func(t *testing.T) {
+			db, _ := Open("file::memory:")
+
+			err := db.Restore(strings.NewReader(document), RestoreOptions{})
+			if assert.NotNil(t, err) {
+				assert.Equal(t, expected, err.Error())
+			}
+		})
+	}
+}
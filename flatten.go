@@ -0,0 +1,192 @@
+package numbersix
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// Flatten returns a copy of the DB that stores values which are objects, such
+// as maps or structs, as subjects of their own. Each key of the object is stored
+// as a predicate of a generated child subject, and the value stored in place of
+// the object is a reference to the child. A key with a list of values has each
+// value stored, and objects within the object are flattened in the same way.
+//
+// This allows queries on the values of objects, for example if a post has an
+// "author" with a "name" then the post can be found, using the returned DB, with
+//
+//    Where("author.name", "John Doe")
+//
+// Use Groups to read flattened subjects with their objects reassembled. Values
+// that are removed, by Replace, DeleteValue, DeletePredicate, DeleteSubject or
+// the deletions of ApplyUpdate, also have their child subjects removed.
+func (d *DB) Flatten() *DB {
+	c := *d
+	c.flatten = true
+	return &c
+}
+
+// Groups returns the triples that match the query provided as groups, like
+// Grouped. Any values that reference a child subject created by Flatten are
+// replaced with an object containing the child's properties, with each key
+// having a list of values.
+func (d *DB) Groups(query Query) ([]Group, error) {
+	return d.store().groups(query)
+}
+
+func (s store) groups(query Query) ([]Group, error) {
+	triples, err := s.list(query)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := Grouped(triples)
+	for _, group := range groups {
+		if err := s.inflate(group); err != nil {
+			return nil, err
+		}
+	}
+
+	return groups, nil
+}
+
+// inflate replaces references to children of the group's subject with their
+// properties.
+func (s store) inflate(group Group) error {
+	for _, values := range group.Properties {
+		for i, value := range values {
+			r, ok := value.(Ref)
+			if !ok || !strings.HasPrefix(string(r), group.Subject+"#") {
+				continue
+			}
+
+			children, err := s.groups(About(string(r)))
+			if err != nil {
+				return err
+			}
+
+			object := map[string]interface{}{}
+			if len(children) > 0 {
+				for key, childValues := range children[0].Properties {
+					object[key] = childValues
+				}
+			}
+			values[i] = object
+		}
+	}
+
+	return nil
+}
+
+// flatten replaces any values in properties that are objects with references
+// to child subjects, returning the properties to set for the subject and each
+// child subject.
+func flatten(subject string, properties map[string][]interface{}) (map[string]map[string][]interface{}, error) {
+	subjects := map[string]map[string][]interface{}{}
+
+	return subjects, flattenInto(subjects, subject, properties)
+}
+
+func flattenInto(subjects map[string]map[string][]interface{}, subject string, properties map[string][]interface{}) error {
+	flat := subjects[subject]
+	if flat == nil {
+		flat = map[string][]interface{}{}
+		subjects[subject] = flat
+	}
+
+	for predicate, values := range properties {
+		for _, value := range values {
+			v, err := marshal(value)
+			if err != nil {
+				return err
+			}
+
+			if kind, _ := typed(v); kind == typeObject {
+				var object map[string]interface{}
+				if err := json.Unmarshal([]byte(v), &object); err != nil {
+					return err
+				}
+
+				child := childSubject(subject, predicate, v)
+				childProperties := map[string][]interface{}{}
+				for key, childValue := range object {
+					if list, ok := childValue.([]interface{}); ok {
+						childProperties[key] = list
+					} else {
+						childProperties[key] = []interface{}{childValue}
+					}
+				}
+
+				if err := flattenInto(subjects, child, childProperties); err != nil {
+					return err
+				}
+				value = Ref(child)
+			}
+
+			flat[predicate] = append(flat[predicate], value)
+		}
+	}
+
+	return nil
+}
+
+// deleteChildren removes the child subjects referenced by the values of the
+// subject's predicate, or of all its predicates if predicate is empty, along
+// with any children they have.
+func (s store) deleteChildren(subject, predicate string) error {
+	qs := "SELECT value FROM " + s.name + " WHERE subject = ? AND value_type = ?"
+	args := []interface{}{subject, typeRef}
+	if predicate != "" {
+		qs += " AND predicate = ?"
+		args = append(args, predicate)
+	}
+
+	rows, err := s.q.QueryContext(s.ctx, qs, args...)
+	if err != nil {
+		return err
+	}
+
+	var children []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return err
+		}
+
+		if child := strings.TrimPrefix(v, refPrefix); strings.HasPrefix(child, subject+"#") {
+			children = append(children, child)
+		}
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		if err := s.deleteChild(child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteChild removes the child subject, along with any children it has.
+func (s store) deleteChild(child string) error {
+	_, err := s.q.ExecContext(s.ctx, "DELETE FROM "+s.name+" WHERE subject = ? OR subject LIKE ? ESCAPE '\\'",
+		child,
+		escapeLike(child+"#")+"%")
+
+	return err
+}
+
+// childSubject generates the subject for an object value. It is derived from
+// the value so that setting the same object again references the same child.
+func childSubject(subject, predicate, value string) string {
+	sum := sha1.Sum([]byte(value))
+
+	return subject + "#" + predicate + "-" + hex.EncodeToString(sum[:6])
+}
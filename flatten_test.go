@@ -0,0 +1,210 @@
+package numbersix
+
+import (
+	"strings"
+	"testing"
+
+	"hawx.me/code/assert"
+)
+
+func TestFlatten(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	flat := db.Flatten()
+
+	author := map[string]interface{}{
+		"name": "John Doe",
+		"url":  []string{"https://john.example.com", "https://doe.example.com"},
+		"org": map[string]interface{}{
+			"name": "Example Inc.",
+		},
+	}
+
+	assert.Nil(flat.SetProperties("post", map[string][]interface{}{
+		"content": {"hello"},
+		"author":  {author},
+	}))
+	assert.Nil(flat.Set("other", "author", map[string]string{"name": "Jane Doe"}))
+
+	triples, err := db.List(About("post"))
+	assert.Nil(err)
+	if assert.Len(triples, 2) {
+		assert.Equal("author", triples[0].Predicate)
+
+		var child string
+		assert.Nil(triples[0].Value(&child))
+		assert.Equal(childSubject("post", "author", `{"name":"John Doe","org":{"name":"Example Inc."},"url":["https://john.example.com","https://doe.example.com"]}`), child)
+
+		var v interface{}
+		assert.Nil(triples[0].Value(&v))
+		assert.Equal(Ref(child), v)
+	}
+
+	triples, err = flat.List(Where("author.name", "John Doe"))
+	assert.Nil(err)
+	assertTriples(t, triples, []pair{
+		{"post", "author"},
+		{"post", "content"},
+	})
+
+	triples, err = flat.List(Where("author.org.name", "Example Inc."))
+	assert.Nil(err)
+	assertTriples(t, triples, []pair{
+		{"post", "author"},
+		{"post", "content"},
+	})
+
+	ok, err := flat.Any(About("other").Where("author.name", "John Doe"))
+	assert.Nil(err)
+	assert.False(ok)
+
+	groups, err := db.Groups(About("post"))
+	assert.Nil(err)
+	if assert.Len(groups, 1) {
+		assert.Equal(map[string][]interface{}{
+			"content": {"hello"},
+			"author": {map[string]interface{}{
+				"name": []interface{}{"John Doe"},
+				"url":  []interface{}{"https://doe.example.com", "https://john.example.com"},
+				"org": []interface{}{map[string]interface{}{
+					"name": []interface{}{"Example Inc."},
+				}},
+			}},
+		}, groups[0].Properties)
+	}
+
+	assert.Nil(flat.DeleteValue("post", "author", author))
+
+	triples, err = db.List(About("post"))
+	assert.Nil(err)
+	assertTriples(t, triples, []pair{
+		{"post", "content"},
+	})
+}
+
+func TestFlattenWithoutObjects(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	assert.Nil(db.Flatten().Set("thing", "tag", "a", "b"))
+	assert.Nil(db.Set("thing", "props", map[string]string{"a": "b"}))
+
+	groups, err := db.Groups(All())
+	assert.Nil(err)
+	if assert.Len(groups, 1) {
+		assert.Equal(map[string][]interface{}{
+			"tag":   {"a", "b"},
+			"props": {map[string]interface{}{"a": "b"}},
+		}, groups[0].Properties)
+	}
+}
+
+func TestFlattenReplace(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	flat := db.Flatten()
+
+	assert.Nil(flat.Set("post", "author", map[string]interface{}{
+		"name": "John Doe",
+		"org":  map[string]string{"name": "Example Inc."},
+	}))
+	assert.Nil(flat.Set("post", "content", map[string]string{"html": "<p>Hi</p>"}))
+
+	assert.Nil(flat.Replace("post", "author", map[string]string{"name": "Jane Doe"}))
+
+	groups, err := db.Groups(All())
+	assert.Nil(err)
+	if assert.Len(groups, 3) {
+		assert.Equal("post", groups[0].Subject)
+		assert.Equal(map[string][]interface{}{
+			"author": {map[string]interface{}{
+				"name": []interface{}{"Jane Doe"},
+			}},
+			"content": {map[string]interface{}{
+				"html": []interface{}{"<p>Hi</p>"},
+			}},
+		}, groups[0].Properties)
+	}
+}
+
+func TestFlattenDelete(t *testing.T) {
+	author := map[string]interface{}{
+		"name": "John Doe",
+		"card": map[string]string{"url": "https://john.example.com"},
+	}
+
+	testCases := map[string]func(flat *DB) error{
+		"DeleteValue": func(flat *DB) error {
+			return flat.DeleteValue("post", "author", author)
+		},
+		"DeletePredicate": func(flat *DB) error {
+			return flat.DeletePredicate("post", "author")
+		},
+		"DeleteSubject": func(flat *DB) error {
+			return flat.DeleteSubject("post")
+		},
+		"ApplyUpdate": func(flat *DB) error {
+			return flat.ApplyUpdate("post", Update{Delete: &Deletion{Predicates: []string{"author"}}})
+		},
+	}
+
+	for name, del := range testCases {
+		t.Run(name, func(t *testing.T) {
+			db, _ := Open("file::memory:")
+			flat := db.Flatten()
+
+			assert.Nil(t, flat.Set("post", "author", author))
+			assert.Nil(t, flat.Set("other", "author", author))
+			assert.Nil(t, del(flat))
+
+			triples, err := db.List(All())
+			assert.Nil(t, err)
+			for _, triple := range triples {
+				assert.False(t, strings.HasPrefix(triple.Subject, "post"), triple.Subject)
+			}
+
+			groups, err := db.Groups(About("other"))
+			assert.Nil(t, err)
+			if assert.Len(t, groups, 1) {
+				assert.Len(t, groups[0].Properties["author"], 1)
+			}
+		})
+	}
+}
+
+func TestFlattenPredicatePaths(t *testing.T) {
+	db, _ := Open("file::memory:")
+	flat := db.Flatten()
+
+	db.Set("post", "author.name", "John Doe")
+	db.Set("reply", "author", Ref("john"))
+	db.Set("john", "name", "John Doe")
+	db.Set("post", "http://schema.org/name", "Hello")
+	db.Set("http://schema", "org/name", "Hello")
+	db.Set("other", "http://schema", Ref("http://schema"))
+
+	testCases := []struct {
+		db        *DB
+		predicate string
+		value     string
+		expected  []string
+	}{
+		{db, "author.name", "John Doe", []string{"post"}},
+		{flat, "author.name", "John Doe", []string{"post", "reply"}},
+		{db, "http://schema.org/name", "Hello", []string{"post"}},
+		{flat, "http://schema.org/name", "Hello", []string{"post"}},
+	}
+
+	for _, tc := range testCases {
+		triples, err := tc.db.List(Where(tc.predicate, tc.value))
+		assert.Nil(t, err)
+
+		var subjects []string
+		for _, group := range Grouped(triples) {
+			subjects = append(subjects, group.Subject)
+		}
+		assert.Equal(t, tc.expected, subjects)
+	}
+}
@@ -0,0 +1,253 @@
+// Package rdf describes how numbersix maps its triples to RDF, so that the
+// packages of this module that read and write RDF formats agree without the
+// details being part of the public API.
+//
+// Subjects that are IRIs are kept as they are, anything else is written as a
+// blank node. Predicates that are not IRIs are placed in Namespace. Values are
+// written as typed literals, except for a Ref which is written in the same way
+// as a subject.
+package rdf
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"hawx.me/code/numbersix/internal/schema"
+)
+
+// Namespace is the IRI that predicates are placed in when written as RDF, if
+// they are not already IRIs.
+const Namespace = "urn:numbersix:"
+
+// IRIs used when reading and writing RDF. Type is the predicate for a node's
+// type, and the others are the datatypes that values are written with.
+const (
+	Prefix      = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	Type        = Prefix + "type"
+	JSON        = Prefix + "JSON"
+	XSDPrefix   = "http://www.w3.org/2001/XMLSchema#"
+	XSDString   = XSDPrefix + "string"
+	XSDInteger  = XSDPrefix + "integer"
+	XSDDecimal  = XSDPrefix + "decimal"
+	XSDDouble   = XSDPrefix + "double"
+	XSDBoolean  = XSDPrefix + "boolean"
+	XSDDateTime = XSDPrefix + "dateTime"
+)
+
+// A Kind is the kind of an RDF term.
+type Kind int
+
+const (
+	KindIRI Kind = iota
+	KindBlank
+	KindLiteral
+)
+
+// IsIRI returns true if s is an absolute IRI, that is it starts with a scheme.
+func IsIRI(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '+' || c == '.' || c == '-'):
+		case i > 0 && c == ':':
+			return true
+		default:
+			return false
+		}
+	}
+
+	return false
+}
+
+// Subject returns how a subject is written. Subjects that are IRIs are kept as
+// they are, anything else is written as a blank node with the label returned by
+// BlankLabel.
+func Subject(subject string) (Kind, string) {
+	if IsIRI(subject) {
+		return KindIRI, subject
+	}
+
+	return KindBlank, BlankLabel(subject)
+}
+
+// Object returns how a marshaled value is written. A Ref is written in the same
+// way as a subject, other values are written as literals with a datatype of
+// xsd:integer, xsd:decimal or xsd:double for numbers, xsd:boolean for booleans,
+// xsd:dateTime for times, and rdf:JSON for null, arrays and objects. Strings are
+// plain literals, so have no datatype.
+func Object(v string) (kind Kind, value, datatype string) {
+	typ, native := schema.Typed(v)
+
+	switch typ {
+	case schema.TypeRef:
+		kind, value = Subject(v[len(schema.RefPrefix):])
+		return kind, value, ""
+
+	case schema.TypeString, schema.TypeTime:
+		var s string
+		if err := json.Unmarshal([]byte(v), &s); err != nil {
+			return KindLiteral, v, ""
+		}
+		if typ == schema.TypeTime {
+			return KindLiteral, s, XSDDateTime
+		}
+		return KindLiteral, s, ""
+
+	case schema.TypeNumber:
+		if _, ok := native.(int64); ok {
+			return KindLiteral, v, XSDInteger
+		}
+		if strings.ContainsAny(v, "eE") {
+			return KindLiteral, v, XSDDouble
+		}
+		return KindLiteral, v, XSDDecimal
+
+	case schema.TypeBool:
+		return KindLiteral, v, XSDBoolean
+	}
+
+	return KindLiteral, v, JSON
+}
+
+// NodeID returns the identifier that a subject is written as. Subjects that are
+// IRIs are kept as they are, anything else is written as a blank node
+// identifier, so "john doe" is written as "_:john_20doe".
+func NodeID(subject string) string {
+	if kind, value := Subject(subject); kind == KindBlank {
+		return "_:" + value
+	}
+
+	return subject
+}
+
+// PredicateIRI returns the IRI that a predicate is written as, placing it in
+// Namespace if it is not already an IRI.
+func PredicateIRI(predicate string) string {
+	if IsIRI(predicate) {
+		return predicate
+	}
+
+	return Namespace + predicate
+}
+
+// Predicate returns the predicate for an IRI, the reverse of PredicateIRI.
+func Predicate(iri string) string {
+	if strings.HasPrefix(iri, Namespace) && len(iri) > len(Namespace) {
+		return iri[len(Namespace):]
+	}
+
+	return iri
+}
+
+// Literal returns the value to store for a literal with the lexical form s and
+// the given datatype. Literals with a datatype that is not known, or a lexical
+// form that is not valid for their datatype, are stored as strings.
+func Literal(s, datatype string) interface{} {
+	switch datatype {
+	case XSDBoolean:
+		switch s {
+		case "true", "1":
+			return true
+		case "false", "0":
+			return false
+		}
+
+	case JSON:
+		if json.Valid([]byte(s)) {
+			return json.RawMessage(s)
+		}
+
+	case XSDInteger, XSDDecimal, XSDDouble, XSDPrefix + "float", XSDPrefix + "int",
+		XSDPrefix + "long", XSDPrefix + "short", XSDPrefix + "byte",
+		XSDPrefix + "nonNegativeInteger", XSDPrefix + "positiveInteger",
+		XSDPrefix + "nonPositiveInteger", XSDPrefix + "negativeInteger",
+		XSDPrefix + "unsignedInt", XSDPrefix + "unsignedLong",
+		XSDPrefix + "unsignedShort", XSDPrefix + "unsignedByte":
+		if n, err := schema.Number(s); err == nil {
+			return n
+		}
+	}
+
+	return s
+}
+
+// NewSubject returns a random subject, for things that do not have one. It is a
+// URN for a version 4 UUID, as described in RFC 4122.
+func NewSubject() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	h := hex.EncodeToString(b)
+	return "urn:uuid:" + h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// BlankNodes gives each blank node label read from a document its own
+// generated subject, like an anonymous blank node, so that the same label in
+// different documents does not name the same subject.
+type BlankNodes map[string]string
+
+// Subject returns the subject generated for the label.
+func (b BlankNodes) Subject(label string) (string, error) {
+	if subject, ok := b[label]; ok {
+		return subject, nil
+	}
+
+	subject, err := NewSubject()
+	if err != nil {
+		return "", err
+	}
+	b[label] = subject
+
+	return subject, nil
+}
+
+// BlankLabel returns a blank node label for the subject. Letters and digits are
+// kept, any other byte is written as "_" followed by its value in hex, so that
+// the subject can be recovered by UnblankLabel.
+func BlankLabel(subject string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(subject); i++ {
+		c := subject[i]
+
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('_')
+			b.WriteString(strings.ToUpper(strconv.FormatUint(uint64(c)|0x100, 16)[1:]))
+		}
+	}
+
+	return b.String()
+}
+
+// UnblankLabel returns the subject for a blank node label. Labels that were not
+// written by BlankLabel are returned mostly as they are, as only "_" followed by
+// two hex digits is changed.
+func UnblankLabel(label string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(label); i++ {
+		if label[i] == '_' && i+2 < len(label) {
+			if c, err := strconv.ParseUint(label[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+
+		b.WriteByte(label[i])
+	}
+
+	return b.String()
+}
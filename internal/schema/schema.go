@@ -0,0 +1,142 @@
+// Package schema describes how numbersix stores triples in sqlite, so that the
+// packages of this module can compile their own SQL against a DB without the
+// details being part of the public API.
+//
+// A DB stores its triples in a table with the columns subject, predicate and
+// value, holding the marshaled value, along with value_type and typed_value
+// which are the type of the value and, for numbers and times, a native value to
+// compare with.
+package schema
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Types of value that can be stored, as recorded in the value_type column.
+const (
+	TypeString = "string"
+	TypeNumber = "number"
+	TypeTime   = "time"
+	TypeBool   = "bool"
+	TypeNull   = "null"
+	TypeArray  = "array"
+	TypeObject = "object"
+	TypeRef    = "ref"
+)
+
+// RefPrefix starts the marshaled form of a Ref. As it can never start valid
+// JSON a Ref will not be confused with any other value.
+const RefPrefix = "@"
+
+// Ordering returns the expression that values are ordered by for the table
+// with the alias given, or the table being selected from if alias is empty.
+// Numbers and times are compared using their typed value, anything else by the
+// marshaled value.
+func Ordering(alias string) string {
+	if alias != "" {
+		alias += "."
+	}
+
+	return "COALESCE(" + alias + "typed_value, " + alias + "value)"
+}
+
+// Typed returns the type of the marshaled data, and for numbers and times a
+// native value that can be compared in sqlite. Numbers are returned as an int64
+// where possible, otherwise a float64. Times, which are strings in RFC3339
+// format, are returned as the number of nanoseconds since the Unix epoch. This
+// is an int64 for times between the years 1678 and 2262, outside of which it
+// would overflow so a float64 is returned instead.
+func Typed(data string) (kind string, native interface{}) {
+	if len(data) == 0 {
+		return TypeNull, nil
+	}
+
+	switch data[0] {
+	case RefPrefix[0]:
+		return TypeRef, nil
+
+	case '"':
+		var s string
+		if err := json.Unmarshal([]byte(data), &s); err == nil {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return TypeTime, unixNano(t)
+			}
+		}
+		return TypeString, nil
+
+	case 't', 'f':
+		return TypeBool, nil
+
+	case 'n':
+		return TypeNull, nil
+
+	case '[':
+		return TypeArray, nil
+
+	case '{':
+		return TypeObject, nil
+
+	default:
+		if i, err := strconv.ParseInt(data, 10, 64); err == nil {
+			return TypeNumber, i
+		}
+		if f, err := strconv.ParseFloat(data, 64); err == nil {
+			return TypeNumber, f
+		}
+		return TypeString, nil
+	}
+}
+
+// Number parses s as an int64 where possible, otherwise as a float64, which is
+// how numbers are stored. Numbers that cannot be marshaled, such as infinity,
+// are an error.
+func Number(s string) (interface{}, error) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, errors.New("number " + strconv.Quote(s) + " cannot be stored")
+	}
+
+	return f, nil
+}
+
+var (
+	minUnixNano = time.Unix(0, math.MinInt64)
+	maxUnixNano = time.Unix(0, math.MaxInt64)
+)
+
+// unixNano returns t as the number of nanoseconds since the Unix epoch. sqlite
+// compares integers and reals by their value, so times that cannot be held in an
+// int64 are still ordered correctly as a float64.
+func unixNano(t time.Time) interface{} {
+	if t.Before(minUnixNano) || t.After(maxUnixNano) {
+		return float64(t.Unix())*1e9 + float64(t.Nanosecond())
+	}
+
+	return t.UnixNano()
+}
+
+// A Table is where a DB stores its triples.
+type Table struct {
+	// Name of the table.
+	Name string
+
+	// Query runs a query against the sqlite database, using the context of the
+	// DB.
+	Query func(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// TableOf returns the Table for a *numbersix.DB. It is set by numbersix, as
+// this package cannot import it.
+var TableOf func(db interface{}) Table
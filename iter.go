@@ -0,0 +1,127 @@
+package numbersix
+
+import (
+	"database/sql"
+)
+
+// A Cursor iterates over the triples matching a query, reading them from the
+// database one at a time. A Cursor must be closed when no longer needed.
+type Cursor struct {
+	rows   *sql.Rows
+	triple Triple
+	err    error
+}
+
+// Iter returns a Cursor over all triples that match the query provided.
+func (d *DB) Iter(query Query) (*Cursor, error) {
+	return d.store().iter(query)
+}
+
+func (s store) iter(query Query) (*Cursor, error) {
+	qs, args := query.build(s.table())
+
+	rows, err := s.q.QueryContext(s.ctx, qs, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cursor{rows: rows}, nil
+}
+
+// Next moves the cursor to the next triple, returning false when there are no
+// more triples or an error occurred.
+func (c *Cursor) Next() bool {
+	if c.err != nil || !c.rows.Next() {
+		return false
+	}
+
+	c.triple = Triple{}
+	if c.err = c.rows.Scan(&c.triple.Subject, &c.triple.Predicate, &c.triple.v); c.err != nil {
+		return false
+	}
+
+	return true
+}
+
+// Triple returns the triple the cursor is at.
+func (c *Cursor) Triple() Triple {
+	return c.triple
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (c *Cursor) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+
+	return c.rows.Err()
+}
+
+// Close the cursor.
+func (c *Cursor) Close() error {
+	return c.rows.Close()
+}
+
+// A GroupCursor iterates over the triples matching a query, reading a Group
+// at a time. Like Grouped, a new Group is produced each time the subject
+// changes, so the query should return triples ordered by subject.
+type GroupCursor struct {
+	cursor  *Cursor
+	group   Group
+	pending []Triple
+}
+
+// IterGrouped returns a GroupCursor over all triples that match the query
+// provided.
+func (d *DB) IterGrouped(query Query) (*GroupCursor, error) {
+	return d.store().iterGrouped(query)
+}
+
+func (s store) iterGrouped(query Query) (*GroupCursor, error) {
+	cursor, err := s.iter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GroupCursor{cursor: cursor}, nil
+}
+
+// Next moves the cursor to the next group, returning false when there are no
+// more groups or an error occurred.
+func (c *GroupCursor) Next() bool {
+	triples := c.pending
+	c.pending = nil
+
+	for c.cursor.Next() {
+		triple := c.cursor.Triple()
+
+		if len(triples) > 0 && triple.Subject != triples[0].Subject {
+			c.pending = []Triple{triple}
+			break
+		}
+
+		triples = append(triples, triple)
+	}
+
+	if len(triples) == 0 || c.cursor.Err() != nil {
+		return false
+	}
+
+	c.group = Grouped(triples)[0]
+	return true
+}
+
+// Group returns the group the cursor is at.
+func (c *GroupCursor) Group() Group {
+	return c.group
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (c *GroupCursor) Err() error {
+	return c.cursor.Err()
+}
+
+// Close the cursor.
+func (c *GroupCursor) Close() error {
+	return c.cursor.Close()
+}
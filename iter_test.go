@@ -0,0 +1,119 @@
+package numbersix
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"hawx.me/code/assert"
+)
+
+func TestIter(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+
+	db.Set("1", "name", "John")
+	db.Set("1", "age", 25)
+	db.Set("2", "name", "Jane")
+	db.Set("2", "age", 23)
+
+	cursor, err := db.Iter(All())
+	assert.Nil(err)
+	defer cursor.Close()
+
+	var triples []Triple
+	for cursor.Next() {
+		triples = append(triples, cursor.Triple())
+	}
+	assert.Nil(cursor.Err())
+
+	assertTriples(t, triples, []pair{
+		{"1", "age"},
+		{"1", "name"},
+		{"2", "age"},
+		{"2", "name"},
+	})
+
+	var name string
+	assert.Nil(triples[3].Value(&name))
+	assert.Equal("Jane", name)
+}
+
+func TestIterGrouped(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+
+	db.Set("1", "name", "John")
+	db.Set("1", "tag", "a", "b")
+	db.Set("2", "name", "Jane")
+	db.Set("3", "name", "Kevin")
+	db.Set("3", "age", 23)
+
+	cursor, err := db.IterGrouped(All())
+	assert.Nil(err)
+	defer cursor.Close()
+
+	var groups []Group
+	for cursor.Next() {
+		groups = append(groups, cursor.Group())
+	}
+	assert.Nil(cursor.Err())
+
+	if assert.Len(groups, 3) {
+		assert.Equal("1", groups[0].Subject)
+		assert.Equal([]interface{}{"John"}, groups[0].Properties["name"])
+		assert.Equal([]interface{}{"a", "b"}, groups[0].Properties["tag"])
+
+		assert.Equal("2", groups[1].Subject)
+		assert.Equal([]interface{}{"Jane"}, groups[1].Properties["name"])
+
+		assert.Equal("3", groups[2].Subject)
+		assert.Equal([]interface{}{"Kevin"}, groups[2].Properties["name"])
+		assert.Equal([]interface{}{float64(23)}, groups[2].Properties["age"])
+	}
+}
+
+func TestIterGroupedEmpty(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+
+	cursor, err := db.IterGrouped(All())
+	assert.Nil(err)
+	defer cursor.Close()
+
+	assert.False(cursor.Next())
+	assert.Nil(cursor.Err())
+}
+
+func TestIterGroupedError(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+
+	db.Set("1", "name", "John")
+	db.Set("2", "name", "Jane")
+	db.Set("2", "tag", "a", "b")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cursor, err := db.WithContext(ctx).IterGrouped(All())
+	assert.Nil(err)
+	defer cursor.Close()
+
+	assert.True(cursor.Next())
+	assert.Equal("1", cursor.Group().Subject)
+
+	// the rows are closed in the background once the context is done, with the
+	// first triple for "2" already read
+	cancel()
+	for deadline := time.Now().Add(time.Second); cursor.Err() == nil && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.False(cursor.Next())
+	assert.Equal(context.Canceled, cursor.Err())
+}
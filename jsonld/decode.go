@@ -0,0 +1,259 @@
+package jsonld
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+
+	"hawx.me/code/numbersix"
+	"hawx.me/code/numbersix/internal/rdf"
+)
+
+// A Decoder reads JSON-LD documents, expanding them into groups.
+type Decoder struct {
+	r io.Reader
+}
+
+// NewDecoder returns a Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads the next document and returns a group for each node in it,
+// ordered as the nodes are found when reading the properties of each node in
+// order of name. Nested nodes are given a group of their own, and referenced
+// with a numbersix.Ref. Nodes without an "@id" are given a generated subject,
+// as is each blank node identifier, such as "_:b0", so that the same identifier
+// in different documents does not name the same subject. This means that blank
+// nodes written by the Encoder, for subjects that are not IRIs, are read back
+// with new subjects. Properties are mapped to predicates in the reverse of the
+// way the Encoder maps them, and properties that do not expand to an IRI are
+// dropped.
+//
+// Values with a datatype of xsd:integer, xsd:decimal or xsd:double are decoded
+// as numbers, xsd:boolean as booleans, and "@json" as JSON. Any other value
+// with a type or language is decoded as a string.
+func (d *Decoder) Decode() ([]numbersix.Group, error) {
+	dec := json.NewDecoder(d.r)
+	dec.UseNumber()
+
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	e := &expander{index: map[string]int{}, blanks: rdf.BlankNodes{}}
+	if err := e.element(doc, newContext()); err != nil {
+		return nil, err
+	}
+
+	return e.groups, nil
+}
+
+// expander collects the groups for the nodes of a document.
+type expander struct {
+	groups []numbersix.Group
+	index  map[string]int
+	blanks rdf.BlankNodes
+}
+
+// subject returns the subject for an expanded "@id", generating one for a
+// blank node identifier.
+func (e *expander) subject(id string) (string, error) {
+	if strings.HasPrefix(id, "_:") {
+		return e.blanks.Subject(id[2:])
+	}
+
+	return id, nil
+}
+
+func (e *expander) add(subject, predicate string, values ...interface{}) {
+	i, ok := e.index[subject]
+	if !ok {
+		i = len(e.groups)
+		e.index[subject] = i
+		e.groups = append(e.groups, numbersix.Group{
+			Subject:    subject,
+			Properties: map[string][]interface{}{},
+		})
+	}
+
+	e.groups[i].Properties[predicate] = append(e.groups[i].Properties[predicate], values...)
+}
+
+func (e *expander) element(v interface{}, c *context) error {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if err := e.element(item, c); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			if local, ok := v["@context"]; ok {
+				var err error
+				if c, err = c.parse(local); err != nil {
+					return err
+				}
+			}
+			return e.element(graph, c)
+		}
+
+		_, err := e.node(v, c)
+		return err
+	}
+
+	return errors.New("expected a node object")
+}
+
+// node adds the properties of the node, returning its subject.
+func (e *expander) node(node map[string]interface{}, c *context) (string, error) {
+	if local, ok := node["@context"]; ok {
+		var err error
+		if c, err = c.parse(local); err != nil {
+			return "", err
+		}
+	}
+
+	var subject string
+	var err error
+	switch id := node["@id"].(type) {
+	case nil:
+		subject, err = rdf.NewSubject()
+	case string:
+		subject, err = e.subject(c.expand(id, false, nil, nil))
+	default:
+		err = errors.New("@id must be a string")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if key == "@type" {
+			types, err := e.types(node[key], c)
+			if err != nil {
+				return "", err
+			}
+			if len(types) > 0 {
+				e.add(subject, rdf.Type, types...)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(key, "@") {
+			continue
+		}
+
+		iri := c.expand(key, true, nil, nil)
+		if !rdf.IsIRI(iri) {
+			continue
+		}
+
+		values, err := e.values(node[key], c.terms[key], c)
+		if err != nil {
+			return "", err
+		}
+		if len(values) > 0 {
+			e.add(subject, rdf.Predicate(iri), values...)
+		}
+	}
+
+	return subject, nil
+}
+
+func (e *expander) types(v interface{}, c *context) ([]interface{}, error) {
+	var names []interface{}
+	if list, ok := v.([]interface{}); ok {
+		names = list
+	} else {
+		names = []interface{}{v}
+	}
+
+	types := make([]interface{}, len(names))
+	for i, name := range names {
+		s, ok := name.(string)
+		if !ok {
+			return nil, errors.New("@type must be a string")
+		}
+		types[i] = numbersix.Ref(c.expand(s, true, nil, nil))
+	}
+
+	return types, nil
+}
+
+// values returns the values to store for a property defined by t.
+func (e *expander) values(v interface{}, t term, c *context) ([]interface{}, error) {
+	if t.typ == "@json" {
+		data, err := json.Marshal(v)
+		return []interface{}{json.RawMessage(data)}, err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+
+	case []interface{}:
+		var values []interface{}
+		for _, item := range v {
+			itemValues, err := e.values(item, t, c)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, itemValues...)
+		}
+		return values, nil
+
+	case map[string]interface{}:
+		if value, ok := v["@value"]; ok {
+			typ, _ := v["@type"].(string)
+			if typ == "@json" {
+				data, err := json.Marshal(value)
+				return []interface{}{json.RawMessage(data)}, err
+			}
+			if s, ok := value.(string); ok && typ != "" {
+				return []interface{}{rdf.Literal(s, c.expand(typ, true, nil, nil))}, nil
+			}
+			if value == nil {
+				return nil, nil
+			}
+			return []interface{}{value}, nil
+		}
+
+		if list, ok := v["@list"]; ok {
+			return e.values(list, t, c)
+		}
+		if set, ok := v["@set"]; ok {
+			return e.values(set, t, c)
+		}
+
+		subject, err := e.node(v, c)
+		return []interface{}{numbersix.Ref(subject)}, err
+
+	case string:
+		switch t.typ {
+		case "":
+			return []interface{}{v}, nil
+		case "@id":
+			subject, err := e.subject(c.expand(v, false, nil, nil))
+			return []interface{}{numbersix.Ref(subject)}, err
+		case "@vocab":
+			subject, err := e.subject(c.expand(v, true, nil, nil))
+			return []interface{}{numbersix.Ref(subject)}, err
+		}
+		return []interface{}{rdf.Literal(v, t.typ)}, nil
+	}
+
+	return []interface{}{v}, nil
+}
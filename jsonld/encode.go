@@ -0,0 +1,151 @@
+package jsonld
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"hawx.me/code/numbersix"
+	"hawx.me/code/numbersix/internal/rdf"
+)
+
+// An Encoder writes groups as compacted JSON-LD documents.
+type Encoder struct {
+	w       io.Writer
+	context map[string]interface{}
+}
+
+// NewEncoder returns an Encoder that writes to w, compacting each document
+// using the context. If the context does not set "@vocab" it is set to
+// numbersix.Namespace.
+func NewEncoder(w io.Writer, context map[string]interface{}) *Encoder {
+	withVocab := map[string]interface{}{"@vocab": numbersix.Namespace}
+	for k, v := range context {
+		withVocab[k] = v
+	}
+
+	return &Encoder{w: w, context: withVocab}
+}
+
+// Encode writes the groups as a document. A single group is written as a node
+// object, otherwise the groups are written as nodes in the "@graph".
+func (e *Encoder) Encode(groups []numbersix.Group) error {
+	c, err := newContext().parse(e.context)
+	if err != nil {
+		return err
+	}
+
+	nodes := make([]interface{}, len(groups))
+	for i, group := range groups {
+		nodes[i] = c.node(group)
+	}
+
+	var doc map[string]interface{}
+	if len(nodes) == 1 {
+		doc = nodes[0].(map[string]interface{})
+	} else {
+		doc = map[string]interface{}{"@graph": nodes}
+	}
+	doc["@context"] = e.context
+
+	return json.NewEncoder(e.w).Encode(doc)
+}
+
+// EncodeQuery writes the groups for the triples matching the query as a
+// document, as Encode does.
+func (e *Encoder) EncodeQuery(db *numbersix.DB, query numbersix.Query) error {
+	groups, err := db.Groups(query)
+	if err != nil {
+		return err
+	}
+
+	return e.Encode(groups)
+}
+
+func (c *context) node(group numbersix.Group) map[string]interface{} {
+	node := map[string]interface{}{"@id": rdf.NodeID(group.Subject)}
+
+	for predicate, values := range group.Properties {
+		iri := rdf.PredicateIRI(predicate)
+
+		if iri == rdf.Type {
+			if types, ok := c.types(values); ok {
+				node["@type"] = types
+				continue
+			}
+		}
+
+		key := c.compact(iri)
+		t := c.terms[key]
+
+		compacted := make([]interface{}, len(values))
+		for i, value := range values {
+			compacted[i] = c.value(value, t)
+		}
+
+		if len(compacted) == 1 && t.container != "@set" && t.container != "@list" {
+			node[key] = compacted[0]
+		} else {
+			node[key] = compacted
+		}
+	}
+
+	return node
+}
+
+// types returns the values as compacted types, if they are all a
+// numbersix.Ref.
+func (c *context) types(values []interface{}) (interface{}, bool) {
+	types := make([]interface{}, len(values))
+	for i, value := range values {
+		ref, ok := value.(numbersix.Ref)
+		if !ok {
+			return nil, false
+		}
+		types[i] = c.compact(string(ref))
+	}
+
+	if len(types) == 1 {
+		return types[0], true
+	}
+	return types, true
+}
+
+// value returns the value compacted for the term. If the term coerces values to
+// a type that the value does not have the value is written in full.
+func (c *context) value(value interface{}, t term) interface{} {
+	switch v := value.(type) {
+	case numbersix.Ref:
+		id := rdf.NodeID(string(v))
+		switch t.typ {
+		case "@id":
+			return id
+		case "@vocab":
+			return c.compact(id)
+		}
+		return map[string]interface{}{"@id": id}
+
+	case string:
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			if t.typ == rdf.XSDDateTime {
+				return v
+			}
+			return map[string]interface{}{"@value": v, "@type": c.compact(rdf.XSDDateTime)}
+		}
+		if t.typ == "" {
+			return v
+		}
+		return map[string]interface{}{"@value": v}
+
+	case nil, []interface{}, map[string]interface{}:
+		if t.typ == "@json" {
+			return v
+		}
+		return map[string]interface{}{"@value": v, "@type": "@json"}
+	}
+
+	if t.typ == "" {
+		return value
+	}
+	return map[string]interface{}{"@value": value}
+}
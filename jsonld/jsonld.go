@@ -0,0 +1,247 @@
+// Package jsonld reads and writes numbersix groups as JSON-LD.
+//
+// Each group is a node with its subject as the "@id", or as a blank node
+// identifier if the subject is not an absolute IRI. Predicates that are not
+// IRIs are placed in numbersix.Namespace, so that by default the context used
+// has "@vocab" set to it. Values stored as a numbersix.Ref are written as node
+// references, times as xsd:dateTime values, and null, arrays and objects as
+// "@json" values. For example, the group
+//
+//    numbersix.Group{
+//      Subject: "https://example.com/1",
+//      Properties: map[string][]interface{}{
+//        "name":   {"Hello"},
+//        "author": {numbersix.Ref("https://example.com/john")},
+//      },
+//    }
+//
+// is encoded with the context {"author": {"@type": "@id"}} as
+//
+//    {
+//      "@context": {"@vocab": "urn:numbersix:", "author": {"@type": "@id"}},
+//      "@id": "https://example.com/1",
+//      "author": "https://example.com/john",
+//      "name": "Hello"
+//    }
+//
+// Only contexts given inline are supported, remote contexts are not fetched.
+package jsonld
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// A term is the definition of a name in a context.
+type term struct {
+	id        string
+	typ       string
+	container string
+}
+
+// context is an active context, with each term's IRI expanded.
+type context struct {
+	vocab string
+	terms map[string]term
+}
+
+func newContext() *context {
+	return &context{terms: map[string]term{}}
+}
+
+// parse returns the context that results from applying v to c.
+func (c *context) parse(v interface{}) (*context, error) {
+	switch v := v.(type) {
+	case nil:
+		return newContext(), nil
+
+	case string:
+		return nil, errors.New("remote context " + v + " is not supported")
+
+	case []interface{}:
+		result := c
+		for _, item := range v {
+			var err error
+			if result, err = result.parse(item); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+
+	case map[string]interface{}:
+		result := &context{vocab: c.vocab, terms: map[string]term{}}
+		for name, t := range c.terms {
+			result.terms[name] = t
+		}
+
+		if vocab, ok := v["@vocab"]; ok {
+			switch vocab := vocab.(type) {
+			case nil:
+				result.vocab = ""
+			case string:
+				result.vocab = result.expand(vocab, true, v, map[string]bool{})
+			default:
+				return nil, errors.New("@vocab must be a string")
+			}
+		}
+
+		defined := map[string]bool{}
+		for name := range v {
+			if err := result.define(v, name, defined); err != nil {
+				return nil, err
+			}
+		}
+
+		return result, nil
+	}
+
+	return nil, errors.New("context must be an object")
+}
+
+// define adds the term name from the local context to c, first defining any
+// term that its IRI depends on.
+func (c *context) define(local map[string]interface{}, name string, defined map[string]bool) error {
+	if defined[name] || strings.HasPrefix(name, "@") {
+		return nil
+	}
+	defined[name] = true
+
+	var t term
+
+	switch v := local[name].(type) {
+	case nil:
+		delete(c.terms, name)
+		return nil
+
+	case string:
+		t.id = v
+
+	case map[string]interface{}:
+		if id, ok := v["@id"].(string); ok {
+			t.id = id
+		} else if strings.Contains(name, ":") {
+			t.id = name
+		} else if c.vocab != "" {
+			t.id = c.vocab + name
+		}
+		if typ, ok := v["@type"].(string); ok {
+			t.typ = typ
+		}
+		switch container := v["@container"].(type) {
+		case string:
+			t.container = container
+		case []interface{}:
+			if len(container) > 0 {
+				t.container, _ = container[0].(string)
+			}
+		}
+
+	default:
+		return errors.New("definition of " + name + " must be a string or object")
+	}
+
+	if t.id == "" {
+		return errors.New("definition of " + name + " must have an @id")
+	}
+
+	t.id = c.expand(t.id, true, local, defined)
+	if t.typ != "" && t.typ != "@id" && t.typ != "@vocab" && t.typ != "@json" {
+		t.typ = c.expand(t.typ, true, local, defined)
+	}
+
+	c.terms[name] = t
+	return nil
+}
+
+// expand returns the IRI for the value. If vocab is true the value may be a
+// term, or relative to the vocabulary. The local context and defined are used
+// to define terms while a context is being parsed, and are otherwise nil.
+func (c *context) expand(value string, vocab bool, local map[string]interface{}, defined map[string]bool) string {
+	if strings.HasPrefix(value, "@") {
+		return value
+	}
+
+	if local != nil {
+		if _, ok := local[value]; ok {
+			c.define(local, value, defined)
+		}
+	}
+
+	if vocab {
+		if t, ok := c.terms[value]; ok {
+			return t.id
+		}
+	}
+
+	if i := strings.Index(value, ":"); i > 0 {
+		prefix, suffix := value[:i], value[i+1:]
+		if prefix == "_" || strings.HasPrefix(suffix, "//") {
+			return value
+		}
+
+		if local != nil {
+			if _, ok := local[prefix]; ok {
+				c.define(local, prefix, defined)
+			}
+		}
+		if t, ok := c.terms[prefix]; ok {
+			return t.id + suffix
+		}
+
+		return value
+	}
+
+	if vocab && c.vocab != "" {
+		return c.vocab + value
+	}
+
+	return value
+}
+
+// compact returns the shortest way of writing the IRI as a property or type.
+// It is written as a term if one is defined for it, relative to the vocabulary,
+// as a compact IRI using a prefix, or otherwise in full.
+func (c *context) compact(iri string) string {
+	names := make([]string, 0, len(c.terms))
+	for name := range c.terms {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) < len(names[j])
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		if c.terms[name].id == iri {
+			return name
+		}
+	}
+
+	if c.vocab != "" && strings.HasPrefix(iri, c.vocab) {
+		// the suffix can't be used if a term of the same name means something
+		// else, or it would be read as a compact IRI
+		suffix := iri[len(c.vocab):]
+		if _, taken := c.terms[suffix]; suffix != "" && !taken && !strings.Contains(suffix, ":") {
+			return suffix
+		}
+	}
+
+	best := iri
+	for _, name := range names {
+		id := c.terms[name].id
+		if strings.Contains(name, ":") || id == "" || !strings.ContainsAny(id[len(id)-1:], ":/?#[]@") {
+			continue
+		}
+
+		if strings.HasPrefix(iri, id) && len(iri) > len(id) && !strings.HasPrefix(iri[len(id):], "//") {
+			if compact := name + ":" + iri[len(id):]; len(compact) < len(best) {
+				best = compact
+			}
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,194 @@
+package jsonld
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"hawx.me/code/assert"
+	"hawx.me/code/numbersix"
+)
+
+func decodeJSON(t *testing.T, data []byte) interface{} {
+	var v interface{}
+	assert.Nil(t, json.Unmarshal(data, &v))
+	return v
+}
+
+func TestEncode(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	err := NewEncoder(&buf, map[string]interface{}{
+		"author": map[string]interface{}{"@type": "@id"},
+	}).Encode([]numbersix.Group{{
+		Subject: "https://example.com/1",
+		Properties: map[string][]interface{}{
+			"name":   {"Hello"},
+			"author": {numbersix.Ref("https://example.com/john")},
+		},
+	}})
+	assert.Nil(err)
+
+	assert.Equal(decodeJSON(t, []byte(`{
+  "@context": {"@vocab": "urn:numbersix:", "author": {"@type": "@id"}},
+  "@id": "https://example.com/1",
+  "author": "https://example.com/john",
+  "name": "Hello"
+}`)), decodeJSON(t, buf.Bytes()))
+}
+
+func TestEncodeQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := numbersix.Open("file::memory:")
+	db.Set("https://example.com/1", "http://www.w3.org/1999/02/22-rdf-syntax-ns#type", numbersix.Ref("http://schema.org/BlogPosting"))
+	db.Set("https://example.com/1", "http://schema.org/headline", "Hello")
+	db.Set("https://example.com/1", "category", "go", "sqlite")
+	db.Set("https://example.com/1", "published", "2019-01-02T03:04:05Z")
+	db.Set("https://example.com/1", "author", numbersix.Ref("john doe"))
+	db.Set("https://example.com/1", "likes", 5)
+	db.Set("https://example.com/1", "content", map[string]string{"html": "<p>Hello</p>"})
+	db.Set("john doe", "name", "John")
+
+	context := map[string]interface{}{
+		"@vocab":    "http://schema.org/",
+		"ex":        "urn:numbersix:",
+		"xsd":       "http://www.w3.org/2001/XMLSchema#",
+		"published": map[string]interface{}{"@id": "ex:published", "@type": "xsd:dateTime"},
+		"tags":      map[string]interface{}{"@id": "ex:category", "@container": "@set"},
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(NewEncoder(&buf, context).EncodeQuery(db, numbersix.All()))
+
+	assert.Equal(decodeJSON(t, []byte(`{
+  "@context": {
+    "@vocab": "http://schema.org/",
+    "ex": "urn:numbersix:",
+    "xsd": "http://www.w3.org/2001/XMLSchema#",
+    "published": {"@id": "ex:published", "@type": "xsd:dateTime"},
+    "tags": {"@id": "ex:category", "@container": "@set"}
+  },
+  "@graph": [{
+    "@id": "https://example.com/1",
+    "@type": "BlogPosting",
+    "headline": "Hello",
+    "tags": ["go", "sqlite"],
+    "published": "2019-01-02T03:04:05Z",
+    "ex:author": {"@id": "_:john_20doe"},
+    "ex:likes": 5,
+    "ex:content": {"@value": {"html": "<p>Hello</p>"}, "@type": "@json"}
+  }, {
+    "@id": "_:john_20doe",
+    "ex:name": "John"
+  }]
+}`)), decodeJSON(t, buf.Bytes()))
+
+	t.Run("round trip", func(t *testing.T) {
+		groups, err := NewDecoder(&buf).Decode()
+		assert.Nil(err)
+
+		other, _ := numbersix.Open("file::memory:")
+		for _, group := range groups {
+			assert.Nil(other.SetProperties(group.Subject, group.Properties))
+		}
+
+		// the blank node for "john doe" is read back with a generated subject
+		john, _ := other.Groups(numbersix.Where("name", "John"))
+		if !assert.Len(john, 1) {
+			return
+		}
+		assert.True(strings.HasPrefix(john[0].Subject, "urn:uuid:"))
+
+		expected, _ := db.Groups(numbersix.All())
+		expected[0].Properties["author"] = []interface{}{numbersix.Ref(john[0].Subject)}
+		expected[1].Subject = john[0].Subject
+
+		actual, _ := other.Groups(numbersix.All())
+		assert.Equal(expected, actual)
+	})
+}
+
+func TestDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	groups, err := NewDecoder(strings.NewReader(`{
+  "@context": [{
+    "schema": "http://schema.org/",
+    "xsd": "http://www.w3.org/2001/XMLSchema#"
+  }, {
+    "name": "schema:name",
+    "url": {"@id": "schema:url", "@type": "@id"},
+    "count": {"@id": "schema:count", "@type": "xsd:integer"},
+    "data": {"@id": "schema:data", "@type": "@json"}
+  }],
+  "@id": "https://example.com/1",
+  "@type": ["schema:BlogPosting", "schema:Thing"],
+  "name": [{"@value": "Hello", "@language": "en"}, "Bonjour"],
+  "url": "https://example.com/one",
+  "count": "12",
+  "data": [1, 2],
+  "schema:published": {"@value": "2019-01-02T03:04:05Z", "@type": "xsd:dateTime"},
+  "schema:draft": {"@value": "true", "@type": "xsd:boolean"},
+  "schema:author": {"@id": "_:john", "name": "John"},
+  "schema:about": {"name": "Anonymous"},
+  "unknown": "dropped"
+}`)).Decode()
+	assert.Nil(err)
+
+	if !assert.Len(groups, 3) {
+		return
+	}
+
+	assert.Equal("https://example.com/1", groups[0].Subject)
+	about, _ := groups[0].Properties["http://schema.org/about"][0].(numbersix.Ref)
+	delete(groups[0].Properties, "http://schema.org/about")
+	author, _ := groups[0].Properties["http://schema.org/author"][0].(numbersix.Ref)
+	delete(groups[0].Properties, "http://schema.org/author")
+
+	assert.Equal(map[string][]interface{}{
+		"http://www.w3.org/1999/02/22-rdf-syntax-ns#type": {numbersix.Ref("http://schema.org/BlogPosting"), numbersix.Ref("http://schema.org/Thing")},
+		"http://schema.org/name":                          {"Hello", "Bonjour"},
+		"http://schema.org/url":                           {numbersix.Ref("https://example.com/one")},
+		"http://schema.org/count":                         {int64(12)},
+		"http://schema.org/data":                          {json.RawMessage("[1,2]")},
+		"http://schema.org/published":                     {"2019-01-02T03:04:05Z"},
+		"http://schema.org/draft":                         {true},
+	}, groups[0].Properties)
+
+	assert.Equal(string(about), groups[1].Subject)
+	assert.True(strings.HasPrefix(groups[1].Subject, "urn:uuid:"))
+	assert.Equal(map[string][]interface{}{
+		"http://schema.org/name": {"Anonymous"},
+	}, groups[1].Properties)
+
+	assert.Equal(string(author), groups[2].Subject)
+	assert.True(strings.HasPrefix(groups[2].Subject, "urn:uuid:"))
+	assert.Equal(map[string][]interface{}{
+		"http://schema.org/name": {"John"},
+	}, groups[2].Properties)
+
+	t.Run("blank nodes are scoped to the document", func(t *testing.T) {
+		const document = `{"@id": "_:b0", "urn:numbersix:name": "Hello", "urn:numbersix:self": {"@id": "_:b0"}}`
+
+		first, err := NewDecoder(strings.NewReader(document)).Decode()
+		assert.Nil(err)
+		second, err := NewDecoder(strings.NewReader(document)).Decode()
+		assert.Nil(err)
+
+		if assert.Len(first, 1) && assert.Len(second, 1) {
+			assert.Equal([]interface{}{numbersix.Ref(first[0].Subject)}, first[0].Properties["self"])
+			assert.NotEqual(first[0].Subject, second[0].Subject)
+		}
+	})
+}
+
+func TestDecodeRemoteContext(t *testing.T) {
+	_, err := NewDecoder(strings.NewReader(`{"@context": "https://schema.org/", "name": "Hello"}`)).Decode()
+
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "remote context https://schema.org/ is not supported", err.Error())
+	}
+}
@@ -2,34 +2,40 @@ package numbersix
 
 import (
 	"database/sql"
+	"strconv"
+	"strings"
+
+	"hawx.me/code/numbersix/internal/schema"
 )
 
 // List returns all triples that match the query provided.
-func (d *DB) List(query Query) (results []Triple, err error) {
-	qs, args := query.build(d.name)
+func (d *DB) List(query Query) ([]Triple, error) {
+	return d.store().list(query)
+}
+
+// Any returns true if there exists a triple matching the query provided.
+func (d *DB) Any(query AnyQuery) (bool, error) {
+	return d.store().exists(query)
+}
 
-	rows, err := d.db.Query(qs, args...)
+func (s store) list(query Query) (results []Triple, err error) {
+	cursor, err := s.iter(query)
 	if err != nil {
 		return
 	}
-	defer rows.Close()
+	defer cursor.Close()
 
-	for rows.Next() {
-		var triple Triple
-		if err = rows.Scan(&triple.Subject, &triple.Predicate, &triple.v); err != nil {
-			return
-		}
-		results = append(results, triple)
+	for cursor.Next() {
+		results = append(results, cursor.Triple())
 	}
 
-	return
+	return results, cursor.Err()
 }
 
-// Any returns true if there exists a triple matching the query provided.
-func (d *DB) Any(query AnyQuery) (ok bool, err error) {
-	qs, args := query.buildAny(d.name)
+func (s store) exists(query AnyQuery) (ok bool, err error) {
+	qs, args := query.buildAny(s.table())
 
-	row := d.db.QueryRow(qs, args...)
+	row := s.q.QueryRowContext(s.ctx, qs, args...)
 
 	var i int
 	if err = row.Scan(&i); err != nil {
@@ -45,30 +51,262 @@ func (d *DB) Any(query AnyQuery) (ok bool, err error) {
 
 // Query defines conditions for triples that List should return.
 type Query interface {
-	build(name string) (string, []interface{})
+	build(t table) (string, []interface{})
 }
 
 // AnyQuery defines conditions for triples that Any should match.
 type AnyQuery interface {
-	buildAny(name string) (string, []interface{})
+	buildAny(t table) (string, []interface{})
+}
+
+// ordering is the expression that ordered queries sort on.
+var ordering = schema.Ordering("")
+
+// selection is what each query is compiled from: the conditions subjects must
+// match, the predicate to order subjects by, and the page of subjects to return.
+// Without a predicate subjects are ordered by name.
+type selection struct {
+	conditions              []Condition
+	predicate               string
+	descending              bool
+	limitCount, offsetCount int
+	token                   *PageToken
+}
+
+func (s *selection) build(t table) (qs string, args []interface{}) {
+	return s.buildColumns(t, false)
+}
+
+// buildPage is the same as build, but each triple is followed by the value its
+// subject was ordered by, or an empty string when unordered.
+func (s *selection) buildPage(t table) (qs string, args []interface{}) {
+	return s.buildColumns(t, true)
+}
+
+func (s *selection) buildColumns(t table, withOrdering bool) (qs string, args []interface{}) {
+	columns := "subject, predicate, value"
+
+	if s.predicate == "" {
+		if withOrdering {
+			columns += ", ''"
+		}
+		return s.buildUnordered(t, columns)
+	}
+
+	if withOrdering {
+		columns += ", page.ordering_value"
+	}
+	return s.buildOrdered(t, columns)
+}
+
+func (s *selection) buildUnordered(t table, columns string) (qs string, args []interface{}) {
+	limit, limitArgs := limitClause(s.limitCount, s.offsetCount)
+
+	if len(s.conditions) == 0 && limit == "" && s.token == nil {
+		return "SELECT " + columns + " FROM " + t.name + " ORDER BY subject, predicate", nil
+	}
+
+	matched, args := And(s.conditions...).subjects(t)
+	qs = "WITH matched(found) AS ( " + matched + " ), " +
+		"page(found) AS ( SELECT found FROM matched "
+
+	if s.token != nil {
+		qs += "WHERE found > ? "
+		args = append(args, s.token.subject)
+	}
+
+	qs += "ORDER BY found " + limit + ") " +
+		"SELECT " + columns + " FROM " + t.name +
+		" INNER JOIN page ON subject = page.found ORDER BY subject, predicate"
+
+	return qs, append(args, limitArgs...)
+}
+
+// buildOrdered selects the subjects ordered by the least, or when descending
+// greatest, value of the predicate. Only values within any range given for the
+// predicate are considered. The value that gave the ordering is kept alongside
+// it, as sqlite takes it from the same row as the MIN or MAX.
+func (s *selection) buildOrdered(t table, columns string) (qs string, args []interface{}) {
+	aggregate, direction := "MIN", ""
+	if s.descending {
+		aggregate, direction = "MAX", " DESC"
+	}
+
+	qs = "WITH "
+	if len(s.conditions) > 0 {
+		matched, matchedArgs := And(s.conditions...).subjects(t)
+		qs += "matched(found) AS ( " + matched + " ), "
+		args = append(args, matchedArgs...)
+	}
+
+	qs += "ordered(found, ordering, ordering_value) AS ( SELECT subject, " + aggregate + "(" + ordering + "), value FROM " + t.name +
+		" WHERE predicate = ?"
+	args = append(args, s.predicate)
+
+	for _, condition := range s.conditions {
+		if r, ok := condition.(*rangeCondition); ok && r.predicate == s.predicate {
+			bounds, boundsArgs := r.bounds()
+			qs += bounds
+			args = append(args, boundsArgs...)
+		}
+	}
+
+	if len(s.conditions) > 0 {
+		qs += " AND subject IN (SELECT found FROM matched)"
+	}
+
+	qs += " GROUP BY subject ), page(found, ordering, ordering_value) AS ( SELECT found, ordering, ordering_value FROM ordered "
+
+	if s.token != nil {
+		condition, conditionArgs := s.token.condition(!s.descending)
+		qs += "WHERE " + condition + " "
+		args = append(args, conditionArgs...)
+	}
+
+	limit, limitArgs := limitClause(s.limitCount, s.offsetCount)
+	qs += "ORDER BY ordering" + direction + ", found" + direction + " " + limit + ") " +
+		"SELECT " + columns + " FROM " + t.name +
+		" INNER JOIN page ON subject = page.found" +
+		" ORDER BY page.ordering" + direction + ", subject" + direction + ", predicate"
+
+	return qs, append(args, limitArgs...)
+}
+
+func (s *selection) buildAny(t table) (string, []interface{}) {
+	if len(s.conditions) == 0 {
+		return "SELECT 1 FROM " + t.name + " LIMIT 1", nil
+	}
+
+	matched, args := And(s.conditions...).subjects(t)
+
+	return "SELECT 1 FROM ( " + matched + " ) LIMIT 1", args
+}
+
+func (s *selection) next(triples []Triple, value string) *PageToken {
+	return nextToken(s.predicate, s.limitCount, triples, value)
+}
+
+// String returns the query as it would be written for Parse.
+func (s *selection) String() string {
+	var parts []string
+
+	if len(s.conditions) > 0 {
+		parts = append(parts, "where "+And(s.conditions...).String())
+	}
+
+	if s.predicate != "" {
+		order := "order by " + formatWord(s.predicate)
+		if s.descending {
+			order += " desc"
+		}
+		parts = append(parts, order)
+	}
+
+	if s.limitCount > 0 {
+		parts = append(parts, "limit "+strconv.Itoa(s.limitCount))
+	}
+
+	if s.offsetCount > 0 {
+		parts = append(parts, "offset "+strconv.Itoa(s.offsetCount))
+	}
+
+	if s.token != nil {
+		parts = append(parts, "continue "+s.token.String())
+	}
+
+	if len(parts) == 0 {
+		return "all"
+	}
+
+	return strings.Join(parts, " ")
 }
 
-type AllQuery struct{}
+type SelectQuery struct {
+	selection
+}
+
+// Select is a query that returns triples for the subjects matching all of the
+// conditions, or all subjects if none are given. The subjects are ordered by
+// name, unless Ascending or Descending is used.
+//
+//    Select(Has("published"), Not(Eq("visibility", "private"))).
+//      Descending("published").
+//      Limit(10)
+func Select(conditions ...Condition) *SelectQuery {
+	return &SelectQuery{selection{conditions: conditions}}
+}
+
+// Ascending changes the query so that subjects are ordered ascending by the
+// value of the predicate. Subjects without the predicate are not returned.
+func (q *SelectQuery) Ascending(predicate string) *SelectQuery {
+	q.predicate = predicate
+	q.descending = false
+	return q
+}
+
+// Descending changes the query so that subjects are ordered descending by the
+// value of the predicate. Subjects without the predicate are not returned.
+func (q *SelectQuery) Descending(predicate string) *SelectQuery {
+	q.predicate = predicate
+	q.descending = true
+	return q
+}
+
+// Limit adds a condition to the query so that only triples for count subjects
+// are returned.
+func (q *SelectQuery) Limit(count int) *SelectQuery {
+	q.limitCount = count
+	return q
+}
+
+// Offset adds a condition to the query so that triples for the first count
+// subjects are skipped.
+func (q *SelectQuery) Offset(count int) *SelectQuery {
+	q.offsetCount = count
+	return q
+}
+
+// Continue adds a condition to the query so that only triples for subjects
+// after the token, as returned by ListPage, are returned.
+func (q *SelectQuery) Continue(token *PageToken) *SelectQuery {
+	q.token = token
+	return q
+}
+
+type AllQuery struct {
+	selection
+}
 
 // All is a query that returns all triples.
 func All() *AllQuery {
 	return &AllQuery{}
 }
 
-func (q *AllQuery) build(name string) (qs string, args []interface{}) {
-	return "SELECT subject, predicate, value FROM " + name + " ORDER BY subject, predicate", []interface{}{}
+// Limit adds a condition to the query so that only triples for count subjects
+// are returned.
+func (q *AllQuery) Limit(count int) *AllQuery {
+	q.limitCount = count
+	return q
+}
+
+// Offset adds a condition to the query so that triples for the first count
+// subjects are skipped.
+func (q *AllQuery) Offset(count int) *AllQuery {
+	q.offsetCount = count
+	return q
 }
 
-type whereClause struct{ predicate, value string }
+// Continue adds a condition to the query so that only triples for subjects
+// after the token, as returned by ListPage, are returned.
+func (q *AllQuery) Continue(token *PageToken) *AllQuery {
+	q.token = token
+	return q
+}
 
 type AboutQuery struct {
-	subject string
-	wheres  []whereClause
+	subject    string
+	depth      int
+	conditions []Condition
 }
 
 // About is a query that returns all triples with a particular subject.
@@ -79,67 +317,130 @@ func About(subject string) *AboutQuery {
 // Where adds a condition to the query so that only triples for subjects that
 // have the predicate and value are returned.
 func (q *AboutQuery) Where(predicate string, value interface{}) *AboutQuery {
-	v, _ := marshal(value)
+	q.conditions = append(q.conditions, Eq(predicate, value))
+	return q
+}
 
-	q.wheres = append(q.wheres, whereClause{
-		predicate: predicate,
-		value:     v,
-	})
+// WhereIn adds a condition to the query so that only triples for subjects that
+// have the predicate and any of the values are returned.
+func (q *AboutQuery) WhereIn(predicate string, values ...interface{}) *AboutQuery {
+	q.conditions = append(q.conditions, in(predicate, values))
+	return q
+}
 
+// AnyOf adds a condition to the query so that only triples for subjects that
+// match any of the conditions are returned.
+func (q *AboutQuery) AnyOf(conditions ...Condition) *AboutQuery {
+	q.conditions = append(q.conditions, Or(conditions...))
 	return q
 }
 
-func (q *AboutQuery) build(name string) (qs string, args []interface{}) {
-	if len(q.wheres) > 0 {
-		subjects := "WITH subjects(found) AS ( "
+// WhereNot adds a condition to the query so that only triples for subjects that
+// do not have the predicate and value are returned.
+func (q *AboutQuery) WhereNot(predicate string, value interface{}) *AboutQuery {
+	q.conditions = append(q.conditions, Not(Eq(predicate, value)))
+	return q
+}
 
-		for i, where := range q.wheres {
-			if i > 0 {
-				subjects += " INTERSECT "
-			}
-			subjects += "SELECT DISTINCT subject FROM " + name + " WHERE predicate = ? AND value = ?"
-			args = append(args, where.predicate, where.value)
-		}
+// Has adds a condition to the query so that only triples for subjects that have
+// the predicate (with any value) are returned.
+func (q *AboutQuery) Has(predicate string) *AboutQuery {
+	q.conditions = append(q.conditions, Has(predicate))
+	return q
+}
+
+// Without adds a condition to the query so that only triples for subjects that
+// do not have the predicate are returned.
+func (q *AboutQuery) Without(predicate string) *AboutQuery {
+	q.conditions = append(q.conditions, Not(Has(predicate)))
+	return q
+}
 
-		subjects += " ) "
+// Expand changes the query so that triples for subjects referenced by a Ref are
+// also returned, following references up to depth times. The triples for the
+// subject are returned first, followed by those for referenced subjects ordered
+// by how many references were followed to reach them.
+func (q *AboutQuery) Expand(depth int) *AboutQuery {
+	q.depth = depth
+	return q
+}
 
-		return subjects +
-			"SELECT subject, predicate, value FROM " + name +
-			" INNER JOIN subjects ON subject = subjects.found" +
-			" WHERE subject = ?", append(args, q.subject)
+// matched returns the common table expression selecting the subjects matching
+// the conditions of the query, if there are any.
+func (q *AboutQuery) matched(t table) (string, []interface{}) {
+	if len(q.conditions) == 0 {
+		return "", nil
 	}
 
-	return "SELECT subject, predicate, value FROM " + name + " WHERE subject = ? ORDER BY predicate", []interface{}{q.subject}
+	matched, args := And(q.conditions...).subjects(t)
+
+	return "matched(found) AS ( " + matched + " )", args
 }
 
-func (q *AboutQuery) buildAny(name string) (qs string, args []interface{}) {
-	if len(q.wheres) > 0 {
-		subjects := "WITH subjects(found) AS ( "
+func (q *AboutQuery) build(t table) (qs string, args []interface{}) {
+	if q.depth > 0 {
+		return q.buildExpanded(t)
+	}
 
-		for i, where := range q.wheres {
-			if i > 0 {
-				subjects += " INTERSECT "
-			}
-			subjects += "SELECT DISTINCT subject FROM " + name + " WHERE predicate = ? AND value = ?"
-			args = append(args, where.predicate, where.value)
-		}
+	matched, args := q.matched(t)
+	if matched == "" {
+		return "SELECT subject, predicate, value FROM " + t.name + " WHERE subject = ? ORDER BY predicate", []interface{}{q.subject}
+	}
 
-		subjects += " ) "
+	return "WITH " + matched +
+		" SELECT subject, predicate, value FROM " + t.name +
+		" WHERE subject = ? AND subject IN (SELECT found FROM matched) ORDER BY predicate", append(args, q.subject)
+}
 
-		return subjects +
-			"SELECT 1 FROM " + name +
-			" INNER JOIN subjects ON subject = subjects.found" +
-			" WHERE subject = ?", append(args, q.subject)
+func (q *AboutQuery) buildExpanded(t table) (qs string, args []interface{}) {
+	qs = "WITH RECURSIVE "
+	anchor := "SELECT ?, 0"
+
+	matched, args := q.matched(t)
+	if matched != "" {
+		qs += matched + ", "
+		anchor = "SELECT found, 0 FROM matched WHERE found = ?"
 	}
 
-	return "SELECT 1 FROM " + name + " WHERE subject = ?", []interface{}{q.subject}
+	qs += "expanded(found, depth) AS ( " + anchor +
+		" UNION SELECT substr(value, " + strconv.Itoa(len(refPrefix)+1) + "), depth + 1 FROM " + t.name +
+		" INNER JOIN expanded ON subject = expanded.found WHERE value_type = ? AND depth < ? ) " +
+		"SELECT subject, predicate, value FROM " + t.name +
+		" INNER JOIN ( SELECT found, MIN(depth) AS depth FROM expanded GROUP BY found ) AS nearest" +
+		" ON subject = nearest.found ORDER BY nearest.depth, subject, predicate"
+	args = append(args, q.subject, typeRef, q.depth)
+
+	return
+}
+
+// String returns the query as it would be written for Parse.
+func (q *AboutQuery) String() string {
+	s := "about " + formatWord(q.subject)
+
+	if len(q.conditions) > 0 {
+		s += " where " + And(q.conditions...).String()
+	}
+
+	if q.depth > 0 {
+		s += " expand " + strconv.Itoa(q.depth)
+	}
+
+	return s
+}
+
+func (q *AboutQuery) buildAny(t table) (qs string, args []interface{}) {
+	matched, args := q.matched(t)
+	if matched == "" {
+		return "SELECT 1 FROM " + t.name + " WHERE subject = ?", []interface{}{q.subject}
+	}
+
+	return "WITH " + matched +
+		" SELECT 1 FROM " + t.name +
+		" WHERE subject = ? AND subject IN (SELECT found FROM matched)", append(args, q.subject)
 }
 
 type WhereQuery struct {
-	begins   whereClause
-	wheres   []whereClause
-	has      []string
-	withouts []string
+	selection
 }
 
 // Where is a query that returns all triples with a particular predicate-value.
@@ -149,98 +450,116 @@ func Where(predicate string, value interface{}) *WhereQuery {
 	return q.Where(predicate, value)
 }
 
+// WhereIn is a query that returns all triples for subjects with a particular
+// predicate and any of the values.
+func WhereIn(predicate string, values ...interface{}) *WhereQuery {
+	q := &WhereQuery{}
+
+	return q.WhereIn(predicate, values...)
+}
+
+// AnyOf is a query that returns all triples for subjects matching any of the
+// conditions. For example, to find all notes and photos:
+//
+//    AnyOf(Eq("post-type", "note"), Eq("post-type", "photo"))
+func AnyOf(conditions ...Condition) *WhereQuery {
+	q := &WhereQuery{}
+
+	return q.AnyOf(conditions...)
+}
+
 // Begins is a query that returns all triples with a particular predicate that
 // begins with the value.
 func Begins(predicate string, value interface{}) *WhereQuery {
-	v, _ := marshal(value)
-
-	q := &WhereQuery{
-		begins: whereClause{
-			predicate: predicate,
-			value:     v,
-		},
-	}
+	q := &WhereQuery{}
 
-	return q
+	return q.Begins(predicate, value)
 }
 
 // Where adds a condition to the query so that only triples for subjects that
 // have the predicate and value are returned.
 func (q *WhereQuery) Where(predicate string, value interface{}) *WhereQuery {
-	v, _ := marshal(value)
+	q.conditions = append(q.conditions, Eq(predicate, value))
+	return q
+}
 
-	q.wheres = append(q.wheres, whereClause{
-		predicate: predicate,
-		value:     v,
-	})
+// WhereIn adds a condition to the query so that only triples for subjects that
+// have the predicate and any of the values are returned.
+func (q *WhereQuery) WhereIn(predicate string, values ...interface{}) *WhereQuery {
+	q.conditions = append(q.conditions, in(predicate, values))
+	return q
+}
+
+// AnyOf adds a condition to the query so that only triples for subjects that
+// match any of the conditions are returned.
+func (q *WhereQuery) AnyOf(conditions ...Condition) *WhereQuery {
+	q.conditions = append(q.conditions, Or(conditions...))
+	return q
+}
 
+// WhereNot adds a condition to the query so that only triples for subjects that
+// do not have the predicate and value are returned.
+func (q *WhereQuery) WhereNot(predicate string, value interface{}) *WhereQuery {
+	q.conditions = append(q.conditions, Not(Eq(predicate, value)))
+	return q
+}
+
+// Begins adds a condition to the query so that only triples for subjects that
+// have the predicate with a value beginning with value are returned.
+func (q *WhereQuery) Begins(predicate string, value interface{}) *WhereQuery {
+	q.conditions = append(q.conditions, Prefix(predicate, value))
 	return q
 }
 
 // Has adds a condition to the query so that only triples for subjects that have
 // the predicate (with any value) are returned.
 func (q *WhereQuery) Has(predicate string) *WhereQuery {
-	q.has = append(q.has, predicate)
-
+	q.conditions = append(q.conditions, Has(predicate))
 	return q
 }
 
 // Without adds a condition to the query so that only triples for subjects that
 // do not have the predicate are returned.
 func (q *WhereQuery) Without(predicate string) *WhereQuery {
-	q.withouts = append(q.withouts, predicate)
-
+	q.conditions = append(q.conditions, Not(Has(predicate)))
 	return q
 }
 
-func (q *WhereQuery) build(name string) (qs string, args []interface{}) {
-	qs = "WITH subjects(found) AS ( "
-
-	if len(q.wheres) > 0 {
-		for i, where := range q.wheres {
-			if i > 0 {
-				qs += " INTERSECT "
-			}
-			qs += "SELECT DISTINCT subject FROM " + name + " WHERE predicate = ? AND value = ?"
-			args = append(args, where.predicate, where.value)
-		}
-	} else {
-		qs += "SELECT DISTINCT subject FROM " + name + " WHERE predicate = ? AND value LIKE ?"
-		args = append(args, q.begins.predicate, q.begins.value[:len(q.begins.value)-1]+"%")
-	}
-
-	for _, has := range q.has {
-		qs += " INTERSECT SELECT DISTINCT subject FROM " + name + " WHERE predicate = ?"
-		args = append(args, has)
-	}
-
-	if len(q.withouts) > 0 {
-		qs += " ), excluded_subjects(found) AS ( "
-
-		for i, without := range q.withouts {
-			if i > 0 {
-				qs += " INTERSECT "
-			}
-			qs += "SELECT DISTINCT subject FROM " + name + " WHERE predicate = ?"
-			args = append(args, without)
-		}
-	}
-
-	qs += " ) SELECT subject, predicate, value FROM " + name + " INNER JOIN subjects ON subject = subjects.found"
+// Limit adds a condition to the query so that only triples for count subjects
+// are returned.
+func (q *WhereQuery) Limit(count int) *WhereQuery {
+	q.limitCount = count
+	return q
+}
 
-	if len(q.withouts) > 0 {
-		qs += " LEFT JOIN excluded_subjects ON subject = excluded_subjects.found WHERE excluded_subjects.found IS NULL"
-	}
+// Offset adds a condition to the query so that triples for the first count
+// subjects are skipped.
+func (q *WhereQuery) Offset(count int) *WhereQuery {
+	q.offsetCount = count
+	return q
+}
 
-	return
+// Continue adds a condition to the query so that only triples for subjects
+// after the token, as returned by ListPage, are returned.
+func (q *WhereQuery) Continue(token *PageToken) *WhereQuery {
+	q.token = token
+	return q
 }
 
 type BoundOrderedQuery struct {
-	predicate, value string
-	ascending        bool
-	limitCount       int
-	wheres           []whereClause
-	withouts         []string
+	selection
+	bounds *rangeCondition
+}
+
+func newBoundOrderedQuery(bounds *rangeCondition, descending bool) *BoundOrderedQuery {
+	return &BoundOrderedQuery{
+		selection: selection{
+			conditions: []Condition{bounds},
+			predicate:  bounds.predicate,
+			descending: descending,
+		},
+		bounds: bounds,
+	}
 }
 
 // After is a query that returns triples for a subject having a triple with the
@@ -262,24 +581,45 @@ type BoundOrderedQuery struct {
 //    ("b", "age", 24)
 //    ("b", "name", "Jane")
 func After(predicate string, value interface{}) *BoundOrderedQuery {
-	v, _ := marshal(value)
-
-	return &BoundOrderedQuery{
+	return newBoundOrderedQuery(&rangeCondition{
 		predicate: predicate,
-		value:     v,
-		ascending: true,
-	}
+		lower:     newBound(value),
+	}, false)
 }
 
 // Before is like After, but the triples returned will have values less than the
 // value given, and will be ordered descending on the predicate.
 func Before(predicate string, value interface{}) *BoundOrderedQuery {
-	v, _ := marshal(value)
+	return newBoundOrderedQuery(&rangeCondition{
+		predicate: predicate,
+		upper:     newBound(value),
+	}, true)
+}
 
-	return &BoundOrderedQuery{
+// Between is like After, but the triples returned will also have values less
+// than high. Unlike After and Before the bounds are inclusive, so values equal
+// to low or high are also returned; use Exclusive to change this.
+func Between(predicate string, low, high interface{}) *BoundOrderedQuery {
+	return newBoundOrderedQuery(&rangeCondition{
 		predicate: predicate,
-		value:     v,
-	}
+		lower:     newBound(low),
+		upper:     newBound(high),
+		inclusive: true,
+	}, false)
+}
+
+// Inclusive changes the query so that values equal to the bounds given are
+// returned.
+func (q *BoundOrderedQuery) Inclusive() *BoundOrderedQuery {
+	q.bounds.inclusive = true
+	return q
+}
+
+// Exclusive changes the query so that values equal to the bounds given are not
+// returned.
+func (q *BoundOrderedQuery) Exclusive() *BoundOrderedQuery {
+	q.bounds.inclusive = false
+	return q
 }
 
 // Limit adds a condition to the query so that only triples for count subjects
@@ -289,173 +629,137 @@ func (q *BoundOrderedQuery) Limit(count int) *BoundOrderedQuery {
 	return q
 }
 
+// Offset adds a condition to the query so that triples for the first count
+// subjects are skipped.
+func (q *BoundOrderedQuery) Offset(count int) *BoundOrderedQuery {
+	q.offsetCount = count
+	return q
+}
+
+// Continue adds a condition to the query so that only triples for subjects
+// after the token, as returned by ListPage, are returned.
+func (q *BoundOrderedQuery) Continue(token *PageToken) *BoundOrderedQuery {
+	q.token = token
+	return q
+}
+
 // Where adds a condition to the query so that only triples for subjects that
 // have the predicate and value are returned.
 func (q *BoundOrderedQuery) Where(predicate string, value interface{}) *BoundOrderedQuery {
-	v, _ := marshal(value)
+	q.conditions = append(q.conditions, Eq(predicate, value))
+	return q
+}
 
-	q.wheres = append(q.wheres, whereClause{
-		predicate: predicate,
-		value:     v,
-	})
+// WhereIn adds a condition to the query so that only triples for subjects that
+// have the predicate and any of the values are returned.
+func (q *BoundOrderedQuery) WhereIn(predicate string, values ...interface{}) *BoundOrderedQuery {
+	q.conditions = append(q.conditions, in(predicate, values))
+	return q
+}
+
+// AnyOf adds a condition to the query so that only triples for subjects that
+// match any of the conditions are returned.
+func (q *BoundOrderedQuery) AnyOf(conditions ...Condition) *BoundOrderedQuery {
+	q.conditions = append(q.conditions, Or(conditions...))
+	return q
+}
 
+// WhereNot adds a condition to the query so that only triples for subjects that
+// do not have the predicate and value are returned.
+func (q *BoundOrderedQuery) WhereNot(predicate string, value interface{}) *BoundOrderedQuery {
+	q.conditions = append(q.conditions, Not(Eq(predicate, value)))
+	return q
+}
+
+// Has adds a condition to the query so that only triples for subjects that have
+// the predicate (with any value) are returned.
+func (q *BoundOrderedQuery) Has(predicate string) *BoundOrderedQuery {
+	q.conditions = append(q.conditions, Has(predicate))
 	return q
 }
 
 // Without adds a condition to the query so that only triples for subjects that
 // do not have the predicate are returned.
 func (q *BoundOrderedQuery) Without(predicate string) *BoundOrderedQuery {
-	q.withouts = append(q.withouts, predicate)
-
+	q.conditions = append(q.conditions, Not(Has(predicate)))
 	return q
 }
 
-func (q *BoundOrderedQuery) build(name string) (qs string, args []interface{}) {
-	var subjects string
-	if len(q.wheres) > 0 {
-		subjects = "subjects(found) AS ( "
-
-		for i, where := range q.wheres {
-			if i > 0 {
-				subjects += " INTERSECT "
-			}
-			subjects += "SELECT DISTINCT subject FROM " + name + " WHERE predicate = ? AND value = ?"
-			args = append(args, where.predicate, where.value)
-		}
-
-		subjects += " ), "
-	}
-
-	var excludedSubjects string
-	if len(q.withouts) > 0 {
-		excludedSubjects = "excluded_subjects(found) AS ( "
-
-		for i, without := range q.withouts {
-			if i > 0 {
-				excludedSubjects += " INTERSECT "
-			}
-			excludedSubjects += "SELECT DISTINCT subject FROM " + name + " WHERE predicate = ?"
-			args = append(args, without)
-		}
-
-		excludedSubjects += " ), "
-	}
-
-	var orderedSubjects string
-	{
-		orderedSubjects = "ordered_subjects(found, ordering) AS ( SELECT DISTINCT subject, value FROM " + name + " "
-		if len(q.wheres) > 0 {
-			orderedSubjects += "INNER JOIN subjects ON subject = subjects.found "
-		}
-
-		where := "WHERE"
-		if len(q.withouts) > 0 {
-			orderedSubjects += "LEFT JOIN excluded_subjects ON subject = excluded_subjects.found WHERE excluded_subjects.found IS NULL "
-			where = "AND"
-		}
-
-		if q.ascending {
-			orderedSubjects += where + " predicate = ? AND value > ? ORDER BY value "
-		} else {
-			orderedSubjects += where + " predicate = ? AND value < ? ORDER BY value DESC "
-		}
-		args = append(args, q.predicate, q.value)
-
-		if q.limitCount > 0 {
-			orderedSubjects += "LIMIT ? "
-			args = append(args, q.limitCount)
-		}
+type OrderedQuery struct {
+	selection
+}
 
-		orderedSubjects += ") "
-	}
+// Ascending is a query that returns triples for all subjects having the
+// predicate, ordered ascending by its value.
+func Ascending(on string) *OrderedQuery {
+	return &OrderedQuery{selection{predicate: on}}
+}
 
-	qs = "SELECT subject, predicate, value FROM ( WITH " +
-		subjects +
-		excludedSubjects +
-		orderedSubjects +
-		`SELECT subject, predicate, value, ordering FROM ` + name + `
-INNER JOIN ordered_subjects ON subject = ordered_subjects.found
-ORDER BY ordering`
-	if !q.ascending {
-		qs += " DESC "
-	}
-	qs += ")"
-	return
+// Descending is a query that returns triples for all subjects having the
+// predicate, ordered descending by its value.
+func Descending(on string) *OrderedQuery {
+	return &OrderedQuery{selection{predicate: on, descending: true}}
 }
 
-type OrderedQuery struct {
-	predicate string
-	ascending bool
-	wheres    []whereClause
+// Limit adds a condition to the query so that only triples for count subjects
+// are returned.
+func (q *OrderedQuery) Limit(count int) *OrderedQuery {
+	q.limitCount = count
+	return q
 }
 
-func Ascending(on string) *OrderedQuery {
-	return &OrderedQuery{
-		ascending: true,
-		predicate: on,
-	}
+// Offset adds a condition to the query so that triples for the first count
+// subjects are skipped.
+func (q *OrderedQuery) Offset(count int) *OrderedQuery {
+	q.offsetCount = count
+	return q
 }
 
-func Descending(on string) *OrderedQuery {
-	return &OrderedQuery{
-		predicate: on,
-	}
+// Continue adds a condition to the query so that only triples for subjects
+// after the token, as returned by ListPage, are returned.
+func (q *OrderedQuery) Continue(token *PageToken) *OrderedQuery {
+	q.token = token
+	return q
 }
 
 // Where adds a condition to the query so that only triples for subjects that
 // have the predicate and value are returned.
 func (q *OrderedQuery) Where(predicate string, value interface{}) *OrderedQuery {
-	v, _ := marshal(value)
-
-	q.wheres = append(q.wheres, whereClause{
-		predicate: predicate,
-		value:     v,
-	})
-
+	q.conditions = append(q.conditions, Eq(predicate, value))
 	return q
 }
 
-func (q *OrderedQuery) build(name string) (qs string, args []interface{}) {
-	var subjects string
-	if len(q.wheres) > 0 {
-		subjects = "subjects(found) AS ( "
-
-		for i, where := range q.wheres {
-			if i > 0 {
-				subjects += " INTERSECT "
-			}
-			subjects += "SELECT DISTINCT subject FROM " + name + " WHERE predicate = ? AND value = ?"
-			args = append(args, where.predicate, where.value)
-		}
+// WhereIn adds a condition to the query so that only triples for subjects that
+// have the predicate and any of the values are returned.
+func (q *OrderedQuery) WhereIn(predicate string, values ...interface{}) *OrderedQuery {
+	q.conditions = append(q.conditions, in(predicate, values))
+	return q
+}
 
-		subjects += " ), "
-	}
+// AnyOf adds a condition to the query so that only triples for subjects that
+// match any of the conditions are returned.
+func (q *OrderedQuery) AnyOf(conditions ...Condition) *OrderedQuery {
+	q.conditions = append(q.conditions, Or(conditions...))
+	return q
+}
 
-	var orderedSubjects string
-	{
-		orderedSubjects = "ordered_subjects(found, ordering) AS ( SELECT DISTINCT subject, value FROM " + name + " "
-		if len(q.wheres) > 0 {
-			orderedSubjects += "INNER JOIN subjects ON subject = subjects.found "
-		}
-		if q.ascending {
-			orderedSubjects += "WHERE predicate = ? ORDER BY value "
-		} else {
-			orderedSubjects += "WHERE predicate = ? ORDER BY value DESC "
-		}
-		args = append(args, q.predicate)
+// WhereNot adds a condition to the query so that only triples for subjects that
+// do not have the predicate and value are returned.
+func (q *OrderedQuery) WhereNot(predicate string, value interface{}) *OrderedQuery {
+	q.conditions = append(q.conditions, Not(Eq(predicate, value)))
+	return q
+}
 
-		orderedSubjects += ") "
-	}
+// Has adds a condition to the query so that only triples for subjects that have
+// the predicate (with any value) are returned.
+func (q *OrderedQuery) Has(predicate string) *OrderedQuery {
+	q.conditions = append(q.conditions, Has(predicate))
+	return q
+}
 
-	qs = "SELECT subject, predicate, value FROM ( WITH " +
-		subjects +
-		orderedSubjects +
-		`SELECT subject, predicate, value, ordering FROM ` + name + `
-INNER JOIN ordered_subjects ON subject = ordered_subjects.found
-ORDER BY ordering`
-	if !q.ascending {
-		qs += " DESC "
-	}
-	qs += ")"
-	return
+// Without adds a condition to the query so that only triples for subjects that
+// do not have the predicate are returned.
+func (q *OrderedQuery) Without(predicate string) *OrderedQuery {
+	q.conditions = append(q.conditions, Not(Has(predicate)))
+	return q
 }
@@ -689,3 +689,430 @@ func TestListDescending(t *testing.T) {
 		}
 	}
 }
+
+func TestListTypedOrdering(t *testing.T) {
+	db, _ := Open("file::memory:")
+
+	db.Set("a", "age", 9)
+	db.Set("b", "age", 10)
+	db.Set("c", "age", 100)
+	db.Set("d", "age", 2.5)
+
+	db.Set("a", "published", time.Date(2019, time.January, 1, 12, 0, 0, 0, time.UTC))
+	db.Set("b", "published", time.Date(2019, time.January, 1, 13, 0, 0, 0, time.FixedZone("", 3*60*60)))
+	db.Set("c", "published", "2019-01-01T11:30:00Z")
+	db.Set("d", "published", "not a time")
+
+	t.Run("After number", func(t *testing.T) {
+		triples, err := db.List(After("age", 9))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"b", "age"}, // 10
+			{"b", "published"},
+			{"c", "age"}, // 100
+			{"c", "published"},
+		})
+	})
+
+	t.Run("Before number", func(t *testing.T) {
+		triples, err := db.List(Before("age", 9.5))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"a", "age"}, // 9
+			{"a", "published"},
+			{"d", "age"}, // 2.5
+			{"d", "published"},
+		})
+	})
+
+	t.Run("Ascending number", func(t *testing.T) {
+		triples, err := db.List(Ascending("age"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"d", "age"}, // 2.5
+			{"d", "published"},
+			{"a", "age"}, // 9
+			{"a", "published"},
+			{"b", "age"}, // 10
+			{"b", "published"},
+			{"c", "age"}, // 100
+			{"c", "published"},
+		})
+	})
+
+	t.Run("After time", func(t *testing.T) {
+		triples, err := db.List(After("published", time.Date(2019, time.January, 1, 9, 0, 0, 0, time.UTC)))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"b", "age"}, // 10:00Z
+			{"b", "published"},
+			{"c", "age"}, // 11:30Z
+			{"c", "published"},
+			{"a", "age"}, // 12:00Z
+			{"a", "published"},
+		})
+	})
+
+	t.Run("Descending time", func(t *testing.T) {
+		triples, err := db.List(Descending("published").Where("age", 10))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"b", "age"},
+			{"b", "published"},
+		})
+	})
+}
+
+func TestListBoundMultipleValues(t *testing.T) {
+	db, _ := Open("file::memory:")
+
+	db.Set("a", "n", 1)
+	db.Set("b", "n", 5, 20)
+	db.Set("c", "n", 10)
+
+	t.Run("After", func(t *testing.T) {
+		triples, err := db.List(After("n", 8))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"c", "n"}, // 10
+			{"b", "n"}, // 20
+			{"b", "n"},
+		})
+	})
+
+	t.Run("Before", func(t *testing.T) {
+		triples, err := db.List(Before("n", 15))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"c", "n"}, // 10
+			{"b", "n"}, // 5
+			{"b", "n"},
+			{"a", "n"}, // 1
+		})
+	})
+
+	t.Run("Between", func(t *testing.T) {
+		triples, err := db.List(Between("n", 4, 30))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"b", "n"}, // 5
+			{"b", "n"},
+			{"c", "n"}, // 10
+		})
+	})
+}
+
+func TestListTimesOutsideUnixNano(t *testing.T) {
+	db, _ := Open("file::memory:")
+
+	db.Set("a", "published", time.Date(3000, time.January, 1, 0, 0, 0, 0, time.UTC))
+	db.Set("b", "published", time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC))
+	db.Set("c", "published", time.Date(1500, time.January, 1, 0, 0, 0, 0, time.UTC))
+	db.Set("d", "published", time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	t.Run("Ascending", func(t *testing.T) {
+		triples, err := db.List(Ascending("published"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"d", "published"},
+			{"c", "published"},
+			{"b", "published"},
+			{"a", "published"},
+		})
+	})
+
+	t.Run("Before", func(t *testing.T) {
+		triples, err := db.List(Before("published", time.Date(1600, time.January, 1, 0, 0, 0, 0, time.UTC)))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"c", "published"},
+			{"d", "published"},
+		})
+	})
+
+	t.Run("After", func(t *testing.T) {
+		triples, err := db.List(After("published", time.Date(2500, time.January, 1, 0, 0, 0, 0, time.UTC)))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"a", "published"},
+		})
+	})
+}
+
+func TestListBetween(t *testing.T) {
+	db, _ := Open("file::memory:")
+
+	db.Set("1", "published", time.Date(2019, time.February, 28, 12, 0, 0, 0, time.UTC))
+	db.Set("2", "published", time.Date(2019, time.March, 1, 0, 0, 0, 0, time.UTC))
+	db.Set("2", "tag", "good")
+	db.Set("3", "published", time.Date(2019, time.March, 14, 12, 0, 0, 0, time.UTC))
+	db.Set("3", "tag", "good")
+	db.Set("4", "published", time.Date(2019, time.March, 20, 12, 0, 0, 0, time.UTC))
+	db.Set("4", "deleted", true)
+	db.Set("5", "published", time.Date(2019, time.March, 31, 0, 0, 0, 0, time.UTC))
+	db.Set("5", "tag", "good")
+	db.Set("6", "published", time.Date(2019, time.April, 1, 12, 0, 0, 0, time.UTC))
+
+	low := time.Date(2019, time.March, 1, 0, 0, 0, 0, time.UTC)
+	high := time.Date(2019, time.March, 31, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Between", func(t *testing.T) {
+		triples, err := db.List(Between("published", low, high))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"2", "published"},
+			{"2", "tag"},
+			{"3", "published"},
+			{"3", "tag"},
+			{"4", "deleted"},
+			{"4", "published"},
+			{"5", "published"},
+			{"5", "tag"},
+		})
+	})
+
+	t.Run("Between Exclusive", func(t *testing.T) {
+		triples, err := db.List(Between("published", low, high).Exclusive())
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"3", "published"},
+			{"3", "tag"},
+			{"4", "deleted"},
+			{"4", "published"},
+		})
+	})
+
+	t.Run("Between with Where and Limit", func(t *testing.T) {
+		triples, err := db.List(Between("published", low, high).Where("tag", "good").Limit(2))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"2", "published"},
+			{"2", "tag"},
+			{"3", "published"},
+			{"3", "tag"},
+		})
+	})
+
+	t.Run("Between with Without", func(t *testing.T) {
+		triples, err := db.List(Between("published", low, high).Without("tag"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"4", "deleted"},
+			{"4", "published"},
+		})
+	})
+
+	t.Run("After Inclusive", func(t *testing.T) {
+		triples, err := db.List(After("published", high).Inclusive())
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"5", "published"},
+			{"5", "tag"},
+			{"6", "published"},
+		})
+	})
+}
+
+func TestListWhereIn(t *testing.T) {
+	db, _ := Open("file::memory:")
+
+	db.Set("1", "post-type", "note")
+	db.Set("1", "published", 1)
+	db.Set("2", "post-type", "photo")
+	db.Set("2", "published", 2)
+	db.Set("3", "post-type", "article")
+	db.Set("3", "published", 3)
+	db.Set("4", "post-type", "photo")
+	db.Set("4", "published", 4)
+	db.Set("4", "visibility", "private")
+
+	t.Run("WhereIn", func(t *testing.T) {
+		triples, err := db.List(WhereIn("post-type", "note", "photo"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"1", "post-type"},
+			{"1", "published"},
+			{"2", "post-type"},
+			{"2", "published"},
+			{"4", "post-type"},
+			{"4", "published"},
+			{"4", "visibility"},
+		})
+	})
+
+	t.Run("WhereIn with Where", func(t *testing.T) {
+		triples, err := db.List(WhereIn("post-type", "note", "photo").Where("visibility", "private"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"4", "post-type"},
+			{"4", "published"},
+			{"4", "visibility"},
+		})
+	})
+
+	t.Run("AnyOf", func(t *testing.T) {
+		triples, err := db.List(AnyOf(Eq("post-type", "article"), Eq("visibility", "private")))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"3", "post-type"},
+			{"3", "published"},
+			{"4", "post-type"},
+			{"4", "published"},
+			{"4", "visibility"},
+		})
+	})
+
+	t.Run("AnyOf with nested Or", func(t *testing.T) {
+		triples, err := db.List(Where("published", 2).AnyOf(
+			Eq("post-type", "article"),
+			Or(Eq("post-type", "note"), Eq("post-type", "photo")),
+		))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"2", "post-type"},
+			{"2", "published"},
+		})
+	})
+
+	t.Run("About with WhereIn", func(t *testing.T) {
+		triples, err := db.List(About("1").WhereIn("post-type", "note", "photo"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"1", "post-type"},
+			{"1", "published"},
+		})
+
+		triples, err = db.List(About("3").WhereIn("post-type", "note", "photo"))
+		assert.Nil(t, err)
+		assert.Len(t, triples, 0)
+	})
+
+	t.Run("Any About with AnyOf", func(t *testing.T) {
+		ok, err := db.Any(About("3").AnyOf(Eq("post-type", "note"), Eq("post-type", "article")))
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("After with WhereIn", func(t *testing.T) {
+		triples, err := db.List(After("published", 1).WhereIn("post-type", "note", "photo"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"2", "post-type"},
+			{"2", "published"},
+			{"4", "post-type"},
+			{"4", "published"},
+			{"4", "visibility"},
+		})
+	})
+
+	t.Run("Descending with AnyOf", func(t *testing.T) {
+		triples, err := db.List(Descending("published").AnyOf(Eq("post-type", "note"), Eq("post-type", "article")))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"3", "post-type"},
+			{"3", "published"},
+			{"1", "post-type"},
+			{"1", "published"},
+		})
+	})
+}
+
+func TestListWhereNot(t *testing.T) {
+	db, _ := Open("file::memory:")
+
+	db.Set("1", "published", 1)
+	db.Set("1", "visibility", "public")
+	db.Set("2", "published", 2)
+	db.Set("2", "visibility", "private")
+	db.Set("3", "published", 3)
+	db.Set("3", "visibility", "unlisted")
+	db.Set("3", "deleted", true)
+	db.Set("4", "published", 4)
+
+	t.Run("Where with WhereNot", func(t *testing.T) {
+		triples, err := db.List(Begins("visibility", "").WhereNot("visibility", "private"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"1", "published"},
+			{"1", "visibility"},
+			{"3", "deleted"},
+			{"3", "published"},
+			{"3", "visibility"},
+		})
+	})
+
+	t.Run("Where with WhereNot and Without", func(t *testing.T) {
+		triples, err := db.List(Begins("visibility", "").WhereNot("visibility", "private").Without("deleted"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"1", "published"},
+			{"1", "visibility"},
+		})
+	})
+
+	t.Run("About with WhereNot", func(t *testing.T) {
+		triples, err := db.List(About("1").WhereNot("visibility", "private"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"1", "published"},
+			{"1", "visibility"},
+		})
+
+		triples, err = db.List(About("2").WhereNot("visibility", "private"))
+		assert.Nil(t, err)
+		assert.Len(t, triples, 0)
+
+		ok, err := db.Any(About("2").WhereNot("visibility", "private"))
+		assert.Nil(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("After with WhereNot", func(t *testing.T) {
+		triples, err := db.List(After("published", 0).WhereNot("visibility", "private").WhereNot("visibility", "unlisted"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"1", "published"},
+			{"1", "visibility"},
+			{"4", "published"},
+		})
+	})
+
+	t.Run("Descending with WhereNot", func(t *testing.T) {
+		triples, err := db.List(Descending("published").WhereNot("visibility", "public").Limit(2))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"4", "published"},
+			{"3", "deleted"},
+			{"3", "published"},
+			{"3", "visibility"},
+		})
+	})
+}
@@ -0,0 +1,276 @@
+package numbersix
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"hawx.me/code/numbersix/internal/rdf"
+)
+
+const (
+	mf2Prefix   = "mf2:"
+	mf2Children = mf2Prefix + "children"
+	mf2Order    = mf2Prefix + "_order"
+)
+
+// ImportMF2 reads a microformats2 JSON document and stores each item, returning
+// the subjects created for the top-level items.
+//
+// An item is stored with the first value of its "url" property as the subject,
+// or a generated subject if it does not have one. The item's types are stored
+// as the "type" predicate, and each property as a predicate of the same name.
+// Embedded microformats, and children, are stored as subjects of their own and
+// referenced with a Ref from the property they appear in. Other keys of the
+// item, such as "id" or "value", are stored as predicates prefixed with "mf2:".
+//
+// As Set does not retain the order of values, the position of each value of a
+// property with more than one is stored in the "mf2:_order" predicate, so that
+// ExportMF2 returns them in the same order. The position of children is part of
+// their subject.
+func (d *DB) ImportMF2(r io.Reader) ([]string, error) {
+	var subjects []string
+
+	err := d.Update(func(tx *Tx) (err error) {
+		subjects, err = tx.ImportMF2(r)
+		return
+	})
+
+	return subjects, err
+}
+
+// ExportMF2 reads the subject, previously stored with ImportMF2, and returns it
+// as a microformats2 item.
+func (d *DB) ExportMF2(subject string) (map[string]interface{}, error) {
+	return exportMF2(d.List, subject)
+}
+
+func (s store) importMF2(r io.Reader) ([]string, error) {
+	var doc struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var subjects []string
+	for _, item := range doc.Items {
+		subject, err := mf2Subject(item)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.importMF2Item(subject, item); err != nil {
+			return nil, err
+		}
+		subjects = append(subjects, subject)
+	}
+
+	return subjects, nil
+}
+
+func mf2Subject(item map[string]interface{}) (string, error) {
+	if properties, ok := item["properties"].(map[string]interface{}); ok {
+		if urls, ok := properties["url"].([]interface{}); ok && len(urls) > 0 {
+			if url, ok := urls[0].(string); ok && url != "" {
+				return url, nil
+			}
+		}
+	}
+
+	return rdf.NewSubject()
+}
+
+func isMF2Item(v interface{}) (map[string]interface{}, bool) {
+	item, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	_, ok = item["type"].([]interface{})
+
+	return item, ok
+}
+
+func (s store) importMF2Item(subject string, item map[string]interface{}) error {
+	properties := map[string][]interface{}{}
+	order := map[string][]string{}
+
+	for key, value := range item {
+		switch key {
+		case "type":
+			types, _ := value.([]interface{})
+			properties["type"] = types
+
+		case "properties":
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return errors.New("mf2 properties must be an object")
+			}
+
+			for predicate, v := range object {
+				values, ok := v.([]interface{})
+				if !ok {
+					return errors.New("mf2 property " + predicate + " must be a list")
+				}
+
+				for i, value := range values {
+					if embedded, ok := isMF2Item(value); ok {
+						child := subject + "#" + predicate + "-" + strconv.Itoa(i)
+						if err := s.importMF2Item(child, embedded); err != nil {
+							return err
+						}
+						value = Ref(child)
+					}
+					properties[predicate] = append(properties[predicate], value)
+				}
+
+				if len(values) > 1 {
+					for _, value := range properties[predicate] {
+						v, err := marshal(value)
+						if err != nil {
+							return err
+						}
+						order[predicate] = append(order[predicate], v)
+					}
+				}
+			}
+
+		case "children":
+			children, _ := value.([]interface{})
+
+			for i, value := range children {
+				embedded, ok := isMF2Item(value)
+				if !ok {
+					return errors.New("mf2 children must be items")
+				}
+
+				child := subject + "#children-" + strconv.Itoa(i)
+				if err := s.importMF2Item(child, embedded); err != nil {
+					return err
+				}
+				properties[mf2Children] = append(properties[mf2Children], Ref(child))
+			}
+
+		default:
+			properties[mf2Prefix+key] = []interface{}{value}
+		}
+	}
+
+	if len(order) > 0 {
+		properties[mf2Order] = []interface{}{order}
+	}
+
+	return s.setProperties(subject, properties)
+}
+
+func exportMF2(list func(Query) ([]Triple, error), subject string) (map[string]interface{}, error) {
+	triples, err := list(About(subject))
+	if err != nil {
+		return nil, err
+	}
+
+	groups := Grouped(triples)
+	if len(groups) == 0 {
+		return nil, ErrNotFound
+	}
+
+	item := map[string]interface{}{}
+	properties := map[string]interface{}{}
+
+	var order map[string][]string
+	if values := groups[0].Properties[mf2Order]; len(values) > 0 {
+		data, err := json.Marshal(values[0])
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &order); err != nil {
+			return nil, err
+		}
+	}
+
+	for predicate, values := range groups[0].Properties {
+		switch {
+		case predicate == mf2Order:
+			// already read, as it is used to order the other properties
+
+		case predicate == "type":
+			item["type"] = values
+
+		case predicate == mf2Children:
+			refs := make([]string, len(values))
+			for i, value := range values {
+				ref, _ := value.(Ref)
+				refs[i] = string(ref)
+			}
+			sort.Slice(refs, func(i, j int) bool {
+				return mf2Index(refs[i]) < mf2Index(refs[j])
+			})
+
+			children := make([]interface{}, len(refs))
+			for i, ref := range refs {
+				if children[i], err = exportMF2(list, ref); err != nil {
+					return nil, err
+				}
+			}
+			item["children"] = children
+
+		case strings.HasPrefix(predicate, mf2Prefix):
+			item[strings.TrimPrefix(predicate, mf2Prefix)] = values[0]
+
+		default:
+			mf2Sort(values, order[predicate])
+
+			exported := make([]interface{}, len(values))
+			for i, value := range values {
+				if ref, ok := value.(Ref); ok {
+					if value, err = exportMF2(list, string(ref)); err != nil {
+						return nil, err
+					}
+				}
+				exported[i] = value
+			}
+			properties[predicate] = exported
+		}
+	}
+
+	item["properties"] = properties
+
+	return item, nil
+}
+
+// mf2Sort sorts the values by their position in order, which lists the values
+// marshaled as they were imported. Any values not in order are kept at the end.
+func mf2Sort(values []interface{}, order []string) {
+	if len(order) == 0 {
+		return
+	}
+
+	positions := map[string]int{}
+	for i, v := range order {
+		if _, ok := positions[v]; !ok {
+			positions[v] = i
+		}
+	}
+
+	position := func(value interface{}) int {
+		if v, err := marshal(value); err == nil {
+			if i, ok := positions[v]; ok {
+				return i
+			}
+		}
+		return len(order)
+	}
+
+	sort.SliceStable(values, func(i, j int) bool {
+		return position(values[i]) < position(values[j])
+	})
+}
+
+func mf2Index(subject string) int {
+	i, _ := strconv.Atoi(subject[strings.LastIndex(subject, "-")+1:])
+
+	return i
+}
@@ -0,0 +1,99 @@
+package numbersix
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"hawx.me/code/assert"
+)
+
+const mf2Document = `{
+  "items": [{
+    "type": ["h-entry"],
+    "id": "post",
+    "properties": {
+      "url": ["https://example.com/post"],
+      "name": ["Hello"],
+      "category": ["foo", "bar"],
+      "photo": ["https://example.com/b.jpg", "https://example.com/a.jpg"],
+      "in-reply-to": [
+        {"type": ["h-cite"], "properties": {"name": ["Reply"]}},
+        "https://example.com/reply"
+      ],
+      "content": [{"html": "<p>Hello <b>world</b></p>", "value": "Hello world"}],
+      "author": [{
+        "type": ["h-card"],
+        "properties": {
+          "name": ["John Doe"],
+          "url": ["https://john.example.com"]
+        },
+        "value": "John Doe"
+      }]
+    },
+    "children": [
+      {"type": ["h-cite"], "properties": {"name": ["First"]}},
+      {"type": ["h-cite"], "properties": {"name": ["Second"]}}
+    ]
+  }, {
+    "type": ["h-card"],
+    "properties": {
+      "name": ["Jane Doe"]
+    }
+  }],
+  "rels": {},
+  "rel-urls": {}
+}`
+
+func TestImportMF2(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+
+	subjects, err := db.ImportMF2(strings.NewReader(mf2Document))
+	assert.Nil(err)
+	if !assert.Len(subjects, 2) {
+		return
+	}
+	assert.Equal("https://example.com/post", subjects[0])
+	assert.Regexp(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, subjects[1])
+
+	ok, err := db.Any(About("https://example.com/post").Where("category", "foo"))
+	assert.Nil(err)
+	assert.True(ok)
+
+	ok, err = db.Any(About("https://example.com/post#author-0").Where("name", "John Doe"))
+	assert.Nil(err)
+	assert.True(ok)
+
+	ok, err = db.Any(About("https://example.com/post#children-1").Where("name", "Second"))
+	assert.Nil(err)
+	assert.True(ok)
+
+	var doc struct {
+		Items []interface{} `json:"items"`
+	}
+	assert.Nil(json.Unmarshal([]byte(mf2Document), &doc))
+
+	for i, subject := range subjects {
+		item, err := db.ExportMF2(subject)
+		assert.Nil(err)
+		assert.Equal(doc.Items[i], roundTrip(t, item))
+	}
+}
+
+func TestExportMF2Missing(t *testing.T) {
+	db, _ := Open("file::memory:")
+
+	_, err := db.ExportMF2("https://example.com/missing")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func roundTrip(t *testing.T, v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	assert.Nil(t, err)
+
+	var out interface{}
+	assert.Nil(t, json.Unmarshal(data, &out))
+	return out
+}
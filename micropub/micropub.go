@@ -0,0 +1,369 @@
+// Package micropub implements a Micropub endpoint that stores posts in a
+// numbersix DB.
+//
+// Each post is stored with its URL as the subject, the microformats type as the
+// "type" predicate, and each property as a predicate. Deleted posts are kept,
+// but marked with the "deleted" predicate so that they can be undeleted; until
+// then a source query for them responds with 410 Gone.
+package micropub
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"hawx.me/code/numbersix"
+)
+
+const (
+	typePredicate    = "type"
+	deletedPredicate = "deleted"
+)
+
+// A Verifier checks that access tokens are valid.
+type Verifier interface {
+	// Verify returns the scopes granted to the token, or an error if the token
+	// is not valid.
+	Verify(token string) (scopes []string, err error)
+}
+
+// VerifierFunc allows a function to be used as a Verifier.
+type VerifierFunc func(token string) ([]string, error)
+
+// Verify calls f(token).
+func (f VerifierFunc) Verify(token string) ([]string, error) {
+	return f(token)
+}
+
+// A SyndicationTarget is somewhere that posts can be syndicated to.
+type SyndicationTarget struct {
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+}
+
+// Endpoint is a http.Handler implementing Micropub.
+type Endpoint struct {
+	// DB is where posts are stored.
+	DB *numbersix.DB
+
+	// Verifier checks the access token given with each request.
+	Verifier Verifier
+
+	// URL returns the URL to use for a new post with the properties given. It is
+	// required to create posts, without it create requests fail.
+	URL func(properties map[string][]interface{}) (string, error)
+
+	// MediaEndpoint is returned in the response to q=config, if set.
+	MediaEndpoint string
+
+	// SyndicateTo is returned in the response to q=config and q=syndicate-to.
+	SyndicateTo []SyndicationTarget
+}
+
+// ServeHTTP handles Micropub queries for GET requests, and creates, updates,
+// deletes and undeletes for POST requests. The DB is used with the context of
+// the request, so work stops if the request is cancelled.
+func (e *Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	db := e.DB.WithContext(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		e.get(w, r, db)
+	case http.MethodPost:
+		e.post(w, r, db)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (e *Endpoint) get(w http.ResponseWriter, r *http.Request, db *numbersix.DB) {
+	if _, ok := e.authorize(w, r, r.FormValue("access_token")); !ok {
+		return
+	}
+
+	syndicateTo := e.SyndicateTo
+	if syndicateTo == nil {
+		syndicateTo = []SyndicationTarget{}
+	}
+
+	switch r.FormValue("q") {
+	case "config":
+		config := map[string]interface{}{
+			"syndicate-to": syndicateTo,
+		}
+		if e.MediaEndpoint != "" {
+			config["media-endpoint"] = e.MediaEndpoint
+		}
+		writeJSON(w, http.StatusOK, config)
+
+	case "syndicate-to":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"syndicate-to": syndicateTo,
+		})
+
+	case "source":
+		e.source(w, r, db)
+
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_request", "unknown query")
+	}
+}
+
+func (e *Endpoint) source(w http.ResponseWriter, r *http.Request, db *numbersix.DB) {
+	url := r.FormValue("url")
+	if url == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "url is required")
+		return
+	}
+
+	triples, err := db.List(numbersix.About(url))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	groups := numbersix.Grouped(triples)
+	if len(groups) == 0 {
+		writeError(w, http.StatusNotFound, "not_found", "no post exists for url")
+		return
+	}
+
+	post := groups[0].Properties
+	if _, ok := post[deletedPredicate]; ok {
+		writeError(w, http.StatusGone, "gone", "post has been deleted")
+		return
+	}
+
+	filter := r.Form["properties[]"]
+	if len(filter) == 0 {
+		filter = r.Form["properties"]
+	}
+
+	if len(filter) > 0 {
+		properties := map[string][]interface{}{}
+		for _, key := range filter {
+			if values, ok := post[key]; ok {
+				properties[key] = values
+			}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"properties": properties,
+		})
+		return
+	}
+
+	types := post[typePredicate]
+	delete(post, typePredicate)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"type":       types,
+		"properties": post,
+	})
+}
+
+// request is a Micropub request, from either a form or JSON body.
+type request struct {
+	Action     string                   `json:"action"`
+	URL        string                   `json:"url"`
+	Type       []string                 `json:"type"`
+	Properties map[string][]interface{} `json:"properties"`
+	numbersix.Update
+
+	token string
+}
+
+func (e *Endpoint) post(w http.ResponseWriter, r *http.Request, db *numbersix.DB) {
+	req, err := parseRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	scopes, ok := e.authorize(w, r, req.token)
+	if !ok {
+		return
+	}
+
+	scope := req.Action
+	if scope == "undelete" {
+		scope = "delete"
+	}
+	if scope == "" {
+		scope = "create"
+	}
+	if !hasScope(scopes, scope) {
+		writeError(w, http.StatusForbidden, "insufficient_scope", "token does not have "+scope+" scope")
+		return
+	}
+
+	switch req.Action {
+	case "":
+		e.create(w, req, db)
+	case "update", "delete", "undelete":
+		e.action(w, req, db)
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_request", "unknown action")
+	}
+}
+
+func (e *Endpoint) create(w http.ResponseWriter, req request, db *numbersix.DB) {
+	if e.URL == nil {
+		writeError(w, http.StatusInternalServerError, "server_error", "endpoint has no URL function")
+		return
+	}
+
+	if len(req.Type) == 0 {
+		req.Type = []string{"h-entry"}
+	}
+
+	properties := map[string][]interface{}{}
+	for key, values := range req.Properties {
+		if !strings.HasPrefix(key, "mp-") {
+			properties[key] = values
+		}
+	}
+
+	url, err := e.URL(properties)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	for _, t := range req.Type {
+		properties[typePredicate] = append(properties[typePredicate], t)
+	}
+
+	if err := db.SetProperties(url, properties); err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	w.Header().Set("Location", url)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (e *Endpoint) action(w http.ResponseWriter, req request, db *numbersix.DB) {
+	if req.URL == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "url is required")
+		return
+	}
+
+	err := db.Update(func(tx *numbersix.Tx) error {
+		ok, err := tx.Any(numbersix.About(req.URL))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errNotFound
+		}
+
+		switch req.Action {
+		case "update":
+			return tx.ApplyUpdate(req.URL, req.Update)
+		case "delete":
+			return tx.Set(req.URL, deletedPredicate, true)
+		default:
+			return tx.DeletePredicate(req.URL, deletedPredicate)
+		}
+	})
+
+	if err == errNotFound {
+		writeError(w, http.StatusBadRequest, "invalid_request", "no post exists for url")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var errNotFound = errors.New("not found")
+
+// authorize checks the access token for the request, taken from the
+// Authorization header or token given. If the token is not valid a response is
+// written and false returned.
+func (e *Endpoint) authorize(w http.ResponseWriter, r *http.Request, token string) ([]string, bool) {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	if token == "" {
+		writeError(w, http.StatusUnauthorized, "unauthorized", "missing access token")
+		return nil, false
+	}
+
+	scopes, err := e.Verifier.Verify(token)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "forbidden", err.Error())
+		return nil, false
+	}
+
+	return scopes, true
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope || (scope == "create" && s == "post") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseRequest(r *http.Request) (req request, err error) {
+	contentType := r.Header.Get("Content-Type")
+
+	if strings.HasPrefix(contentType, "application/json") {
+		err = json.NewDecoder(r.Body).Decode(&req)
+		return
+	}
+
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		err = r.ParseMultipartForm(32 << 20)
+	} else {
+		err = r.ParseForm()
+	}
+	if err != nil {
+		return
+	}
+
+	req.Properties = map[string][]interface{}{}
+	for key, values := range r.PostForm {
+		switch key {
+		case "access_token":
+			req.token = values[0]
+		case "action":
+			req.Action = values[0]
+		case "url":
+			req.URL = values[0]
+		case "h":
+			req.Type = []string{"h-" + values[0]}
+		default:
+			key = strings.TrimSuffix(key, "[]")
+			for _, value := range values {
+				req.Properties[key] = append(req.Properties[key], value)
+			}
+		}
+	}
+
+	return
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
@@ -0,0 +1,322 @@
+package micropub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"hawx.me/code/assert"
+	"hawx.me/code/numbersix"
+)
+
+func newEndpoint(t *testing.T) (*Endpoint, *numbersix.DB) {
+	db, err := numbersix.Open("file::memory:")
+	assert.Nil(t, err)
+
+	count := 0
+
+	return &Endpoint{
+		DB: db,
+		Verifier: VerifierFunc(func(token string) ([]string, error) {
+			switch token {
+			case "full":
+				return []string{"create", "update", "delete"}, nil
+			case "create":
+				return []string{"create"}, nil
+			default:
+				return nil, errors.New("invalid token")
+			}
+		}),
+		URL: func(properties map[string][]interface{}) (string, error) {
+			count++
+			return "https://example.com/" + strconv.Itoa(count), nil
+		},
+		MediaEndpoint: "https://example.com/media",
+		SyndicateTo: []SyndicationTarget{
+			{UID: "https://social.example/", Name: "Social"},
+		},
+	}, db
+}
+
+func do(endpoint http.Handler, method, path, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Authorization", "Bearer full")
+
+	w := httptest.NewRecorder()
+	endpoint.ServeHTTP(w, req)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	var v map[string]interface{}
+	assert.Nil(t, json.NewDecoder(w.Body).Decode(&v))
+	return v
+}
+
+func TestCreateForm(t *testing.T) {
+	assert := assert.New(t)
+	endpoint, db := newEndpoint(t)
+
+	w := do(endpoint, "POST", "/", "application/x-www-form-urlencoded", url.Values{
+		"h":          {"entry"},
+		"content":    {"hello world"},
+		"category[]": {"foo", "bar"},
+		"mp-slug":    {"hello"},
+	}.Encode())
+
+	assert.Equal(http.StatusCreated, w.Code)
+	assert.Equal("https://example.com/1", w.Header().Get("Location"))
+
+	triples, err := db.List(numbersix.About("https://example.com/1"))
+	assert.Nil(err)
+
+	groups := numbersix.Grouped(triples)
+	if assert.Len(groups, 1) {
+		assert.Equal(map[string][]interface{}{
+			"type":     {"h-entry"},
+			"content":  {"hello world"},
+			"category": {"bar", "foo"},
+		}, groups[0].Properties)
+	}
+}
+
+func TestCreateJSON(t *testing.T) {
+	assert := assert.New(t)
+	endpoint, db := newEndpoint(t)
+
+	w := do(endpoint, "POST", "/", "application/json", `{
+  "type": ["h-entry"],
+  "properties": {
+    "content": ["hello world"],
+    "author": [{"type": ["h-card"], "properties": {"name": ["John"]}}]
+  }
+}`)
+
+	assert.Equal(http.StatusCreated, w.Code)
+	assert.Equal("https://example.com/1", w.Header().Get("Location"))
+
+	triples, err := db.List(numbersix.About("https://example.com/1"))
+	assert.Nil(err)
+
+	groups := numbersix.Grouped(triples)
+	if assert.Len(groups, 1) {
+		assert.Equal(map[string][]interface{}{
+			"type":    {"h-entry"},
+			"content": {"hello world"},
+			"author": {map[string]interface{}{
+				"type":       []interface{}{"h-card"},
+				"properties": map[string]interface{}{"name": []interface{}{"John"}},
+			}},
+		}, groups[0].Properties)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	assert := assert.New(t)
+	endpoint, db := newEndpoint(t)
+
+	db.SetProperties("https://example.com/post", map[string][]interface{}{
+		"type":     {"h-entry"},
+		"content":  {"hello world"},
+		"category": {"foo"},
+	})
+
+	w := do(endpoint, "POST", "/", "application/json", `{
+  "action": "update",
+  "url": "https://example.com/post",
+  "replace": {"content": ["hello moon"]},
+  "add": {"category": ["moon"]}
+}`)
+	assert.Equal(http.StatusNoContent, w.Code)
+
+	triples, _ := db.List(numbersix.About("https://example.com/post"))
+	groups := numbersix.Grouped(triples)
+	if assert.Len(groups, 1) {
+		assert.Equal(map[string][]interface{}{
+			"type":     {"h-entry"},
+			"content":  {"hello moon"},
+			"category": {"foo", "moon"},
+		}, groups[0].Properties)
+	}
+
+	w = do(endpoint, "POST", "/", "application/json", `{
+  "action": "update",
+  "url": "https://example.com/missing",
+  "replace": {"content": ["hello moon"]}
+}`)
+	assert.Equal(http.StatusBadRequest, w.Code)
+	assert.Equal("invalid_request", decode(t, w)["error"])
+}
+
+func TestDeleteAndUndelete(t *testing.T) {
+	assert := assert.New(t)
+	endpoint, db := newEndpoint(t)
+
+	db.SetProperties("https://example.com/post", map[string][]interface{}{
+		"type":    {"h-entry"},
+		"content": {"hello world"},
+	})
+
+	w := do(endpoint, "POST", "/", "application/x-www-form-urlencoded", url.Values{
+		"action": {"delete"},
+		"url":    {"https://example.com/post"},
+	}.Encode())
+	assert.Equal(http.StatusNoContent, w.Code)
+
+	ok, _ := db.Any(numbersix.About("https://example.com/post").Where("deleted", true))
+	assert.True(ok)
+
+	w = do(endpoint, "POST", "/", "application/json", `{"action": "undelete", "url": "https://example.com/post"}`)
+	assert.Equal(http.StatusNoContent, w.Code)
+
+	ok, _ = db.Any(numbersix.About("https://example.com/post").Where("deleted", true))
+	assert.False(ok)
+}
+
+func TestQuery(t *testing.T) {
+	endpoint, db := newEndpoint(t)
+
+	db.SetProperties("https://example.com/post", map[string][]interface{}{
+		"type":     {"h-entry"},
+		"content":  {"hello world"},
+		"category": {"foo", "bar"},
+	})
+	db.SetProperties("https://example.com/deleted", map[string][]interface{}{
+		"type":    {"h-entry"},
+		"content": {"goodbye"},
+		"deleted": {true},
+	})
+
+	t.Run("config", func(t *testing.T) {
+		assert := assert.New(t)
+
+		w := do(endpoint, "GET", "/?q=config", "", "")
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal(map[string]interface{}{
+			"media-endpoint": "https://example.com/media",
+			"syndicate-to": []interface{}{
+				map[string]interface{}{"uid": "https://social.example/", "name": "Social"},
+			},
+		}, decode(t, w))
+	})
+
+	t.Run("syndicate-to", func(t *testing.T) {
+		assert := assert.New(t)
+
+		w := do(endpoint, "GET", "/?q=syndicate-to", "", "")
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal(map[string]interface{}{
+			"syndicate-to": []interface{}{
+				map[string]interface{}{"uid": "https://social.example/", "name": "Social"},
+			},
+		}, decode(t, w))
+	})
+
+	t.Run("source", func(t *testing.T) {
+		assert := assert.New(t)
+
+		w := do(endpoint, "GET", "/?q=source&url=https://example.com/post", "", "")
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal(map[string]interface{}{
+			"type": []interface{}{"h-entry"},
+			"properties": map[string]interface{}{
+				"content":  []interface{}{"hello world"},
+				"category": []interface{}{"bar", "foo"},
+			},
+		}, decode(t, w))
+	})
+
+	t.Run("source with properties", func(t *testing.T) {
+		assert := assert.New(t)
+
+		w := do(endpoint, "GET", "/?q=source&url=https://example.com/post&properties[]=content&properties[]=name", "", "")
+		assert.Equal(http.StatusOK, w.Code)
+		assert.Equal(map[string]interface{}{
+			"properties": map[string]interface{}{
+				"content": []interface{}{"hello world"},
+			},
+		}, decode(t, w))
+	})
+
+	t.Run("source missing", func(t *testing.T) {
+		w := do(endpoint, "GET", "/?q=source&url=https://example.com/missing", "", "")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("source deleted", func(t *testing.T) {
+		w := do(endpoint, "GET", "/?q=source&url=https://example.com/deleted", "", "")
+		assert.Equal(t, http.StatusGone, w.Code)
+		assert.Equal(t, "gone", decode(t, w)["error"])
+	})
+}
+
+func TestCreateWithoutURL(t *testing.T) {
+	assert := assert.New(t)
+	endpoint, db := newEndpoint(t)
+	endpoint.URL = nil
+
+	w := do(endpoint, "POST", "/", "application/x-www-form-urlencoded", url.Values{
+		"h":       {"entry"},
+		"content": {"hello world"},
+	}.Encode())
+	assert.Equal(http.StatusInternalServerError, w.Code)
+	assert.Equal("server_error", decode(t, w)["error"])
+
+	ok, _ := db.Any(numbersix.All())
+	assert.False(ok)
+}
+
+func TestRequestContext(t *testing.T) {
+	assert := assert.New(t)
+	endpoint, db := newEndpoint(t)
+
+	db.SetProperties("https://example.com/post", map[string][]interface{}{
+		"type":    {"h-entry"},
+		"content": {"hello world"},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest("GET", "/?q=source&url=https://example.com/post", nil).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer full")
+	w := httptest.NewRecorder()
+	endpoint.ServeHTTP(w, req)
+	assert.Equal(http.StatusInternalServerError, w.Code)
+	assert.Equal(context.Canceled.Error(), decode(t, w)["error_description"])
+}
+
+func TestAuthorization(t *testing.T) {
+	assert := assert.New(t)
+	endpoint, _ := newEndpoint(t)
+
+	req := httptest.NewRequest("GET", "/?q=config", nil)
+	w := httptest.NewRecorder()
+	endpoint.ServeHTTP(w, req)
+	assert.Equal(http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest("GET", "/?q=config&access_token=bad", nil)
+	w = httptest.NewRecorder()
+	endpoint.ServeHTTP(w, req)
+	assert.Equal(http.StatusForbidden, w.Code)
+
+	req = httptest.NewRequest("POST", "/", strings.NewReader(url.Values{
+		"access_token": {"create"},
+		"action":       {"delete"},
+		"url":          {"https://example.com/post"},
+	}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	endpoint.ServeHTTP(w, req)
+	assert.Equal(http.StatusForbidden, w.Code)
+	assert.Equal("insufficient_scope", decode(t, w)["error"])
+}
@@ -0,0 +1,466 @@
+package numbersix
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"hawx.me/code/numbersix/internal/rdf"
+)
+
+// importBatchSize is the number of statements stored in each transaction by
+// DB.ImportNTriples and DB.ImportNQuads.
+const importBatchSize = 1000
+
+// ExportNTriples writes the triples matching the query to w in the N-Triples
+// format. Triples are read from the database as they are written, so the query
+// may match more triples than would fit in memory.
+//
+// Subjects that are IRIs, such as "https://example.com/post", are written as
+// IRIs; any other subject is written as a blank node. Predicates that are not
+// IRIs are placed in Namespace. Values are written as literals with a datatype
+// of xsd:integer, xsd:decimal or xsd:double for numbers, xsd:boolean for
+// booleans, xsd:dateTime for times, and rdf:JSON for null, arrays and objects;
+// strings are plain literals. A Ref is written in the same way as a subject.
+//
+// A blank node only names a node within the document it is in, so importing the
+// output with ImportNTriples does not keep subjects that are not IRIs: "john" is
+// written as "_:john", but read back with a generated subject. Use Dump and
+// Restore to copy triples without renaming any subjects.
+func (d *DB) ExportNTriples(w io.Writer, query Query) error {
+	return d.store().exportNQuads(w, query, "")
+}
+
+// ExportNQuads writes the triples matching the query to w in the N-Quads format,
+// as ExportNTriples does, with each in the named graph. If graph is empty the
+// triples are written to the default graph, which is the same as
+// ExportNTriples.
+func (d *DB) ExportNQuads(w io.Writer, query Query, graph string) error {
+	return d.store().exportNQuads(w, query, graph)
+}
+
+// ImportNTriples reads a document in the N-Triples format and stores each
+// triple, mapping terms in the same way as ExportNTriples. Triples are stored
+// in batches, each in its own transaction, so if an error is returned the
+// triples read before it may have been stored.
+//
+// Each blank node label is given a generated subject, the same for every use of
+// the label within the document, so labels in different documents never name
+// the same subject. This includes the blank nodes written by ExportNTriples for
+// subjects that are not IRIs, so those subjects are renamed by a round-trip.
+//
+// Numbers are stored as the value they represent, so "007" is stored as 7.
+// Literals with a language tag, or a datatype that is not known, are stored as
+// strings.
+func (d *DB) ImportNTriples(r io.Reader) error {
+	return d.importNQuads(newQuadReader(r, false), "")
+}
+
+// ImportNQuads reads a document in the N-Quads format and stores each triple in
+// the named graph, ignoring those in any other graph. If graph is empty the
+// triples in the default graph are stored.
+func (d *DB) ImportNQuads(r io.Reader, graph string) error {
+	return d.importNQuads(newQuadReader(r, true), graph)
+}
+
+func (d *DB) importNQuads(qr *quadReader, graph string) error {
+	for done := false; !done; {
+		err := d.Update(func(tx *Tx) error {
+			for i := 0; i < importBatchSize; i++ {
+				ok, err := tx.store().importQuad(qr, graph)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					done = true
+					return nil
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s store) importNQuads(qr *quadReader, graph string) error {
+	for {
+		ok, err := s.importQuad(qr, graph)
+		if err != nil || !ok {
+			return err
+		}
+	}
+}
+
+// importQuad reads the next statement in graph and stores it, returning false
+// when there are no more statements.
+func (s store) importQuad(qr *quadReader, graph string) (bool, error) {
+	for {
+		q, err := qr.next()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		var name string
+		if q.graph != nil {
+			name = q.graph.subject()
+		}
+		if name != graph {
+			continue
+		}
+
+		subject, err := qr.blanks.resolve(q.subject)
+		if err != nil {
+			return false, err
+		}
+		object, err := qr.blanks.resolve(q.object)
+		if err != nil {
+			return false, err
+		}
+
+		return true, s.set(subject.subject(), q.predicate.predicate(), object.object())
+	}
+}
+
+func (s store) exportNQuads(w io.Writer, query Query, graph string) error {
+	cursor, err := s.iter(query)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	var suffix string
+	if graph != "" {
+		suffix = " " + subjectTerm(graph).ntriples()
+	}
+	suffix += " .\n"
+
+	bw := bufio.NewWriter(w)
+
+	for cursor.Next() {
+		triple := cursor.Triple()
+
+		bw.WriteString(subjectTerm(triple.Subject).ntriples())
+		bw.WriteByte(' ')
+		bw.WriteString(predicateTerm(triple.Predicate).ntriples())
+		bw.WriteByte(' ')
+		bw.WriteString(objectTerm(triple.v).ntriples())
+		if _, err := bw.WriteString(suffix); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// ntriples returns the term as written in N-Triples.
+func (t rdfTerm) ntriples() string {
+	switch t.kind {
+	case iriTerm:
+		return "<" + escapeIRI(t.value) + ">"
+
+	case blankTerm:
+		return "_:" + t.value
+	}
+
+	s := `"` + literalEscaper.Replace(t.value) + `"`
+	if t.language != "" {
+		return s + "@" + t.language
+	}
+	if t.datatype != "" && t.datatype != rdf.XSDString {
+		return s + "^^<" + escapeIRI(t.datatype) + ">"
+	}
+
+	return s
+}
+
+var literalEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+
+// escapeIRI writes the characters that may not appear in an IRI as \u escapes.
+func escapeIRI(iri string) string {
+	var b strings.Builder
+
+	for _, r := range iri {
+		if r <= ' ' || strings.ContainsRune("<>\"{}|^`\\", r) {
+			b.WriteString(`\u`)
+			b.WriteString(strings.ToUpper(strconv.FormatUint(uint64(r)|0x10000, 16)[1:]))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// A quad is a statement read from an N-Triples or N-Quads document. The graph
+// is nil for statements in the default graph.
+type quad struct {
+	subject, predicate, object rdfTerm
+	graph                      *rdfTerm
+}
+
+// quadReader reads statements a line at a time.
+type quadReader struct {
+	r      *bufio.Reader
+	quads  bool
+	line   int
+	blanks blankNodes
+
+	s   string
+	pos int
+}
+
+func newQuadReader(r io.Reader, quads bool) *quadReader {
+	return &quadReader{r: bufio.NewReader(r), quads: quads, blanks: blankNodes{}}
+}
+
+// next returns the next statement, or io.EOF when there are none.
+func (qr *quadReader) next() (quad, error) {
+	for {
+		line, err := qr.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return quad{}, err
+		}
+
+		qr.line++
+		qr.s, qr.pos = strings.TrimRight(line, "\r\n"), 0
+
+		qr.skipSpace()
+		if qr.done() {
+			continue
+		}
+
+		return qr.statement()
+	}
+}
+
+func (qr *quadReader) error(message string) error {
+	return errors.New("line " + strconv.Itoa(qr.line) + ": " + message)
+}
+
+func (qr *quadReader) skipSpace() {
+	for qr.pos < len(qr.s) && (qr.s[qr.pos] == ' ' || qr.s[qr.pos] == '\t') {
+		qr.pos++
+	}
+}
+
+// done returns true if there is nothing but a comment left on the line.
+func (qr *quadReader) done() bool {
+	return qr.pos == len(qr.s) || qr.s[qr.pos] == '#'
+}
+
+func (qr *quadReader) statement() (q quad, err error) {
+	if q.subject, err = qr.term(); err != nil {
+		return
+	}
+	if q.subject.kind == literalTerm {
+		return q, qr.error("subject must be an IRI or blank node")
+	}
+
+	if q.predicate, err = qr.term(); err != nil {
+		return
+	}
+	if q.predicate.kind != iriTerm {
+		return q, qr.error("predicate must be an IRI")
+	}
+
+	if q.object, err = qr.term(); err != nil {
+		return
+	}
+
+	qr.skipSpace()
+	if qr.quads && qr.pos < len(qr.s) && qr.s[qr.pos] != '.' {
+		graph, err := qr.term()
+		if err != nil {
+			return q, err
+		}
+		if graph.kind == literalTerm {
+			return q, qr.error("graph must be an IRI or blank node")
+		}
+		q.graph = &graph
+		qr.skipSpace()
+	}
+
+	if qr.pos == len(qr.s) || qr.s[qr.pos] != '.' {
+		return q, qr.error("expected '.'")
+	}
+	qr.pos++
+
+	qr.skipSpace()
+	if !qr.done() {
+		return q, qr.error("unexpected " + strconv.Quote(qr.s[qr.pos:]))
+	}
+
+	return q, nil
+}
+
+func (qr *quadReader) term() (rdfTerm, error) {
+	qr.skipSpace()
+	if qr.pos == len(qr.s) {
+		return rdfTerm{}, qr.error("unexpected end of line")
+	}
+
+	switch qr.s[qr.pos] {
+	case '<':
+		iri, err := qr.iri()
+		return rdfTerm{kind: iriTerm, value: iri}, err
+
+	case '_':
+		if !strings.HasPrefix(qr.s[qr.pos:], "_:") {
+			break
+		}
+		start := qr.pos + 2
+		end := start
+		for end < len(qr.s) && !strings.ContainsRune(" \t<\"", rune(qr.s[end])) {
+			end++
+		}
+		// a label can contain "." but not end with it, as that ends the
+		// statement
+		for end > start && qr.s[end-1] == '.' {
+			end--
+		}
+		if end == start {
+			return rdfTerm{}, qr.error("expected blank node label")
+		}
+		qr.pos = end
+		return rdfTerm{kind: blankTerm, value: qr.s[start:end]}, nil
+
+	case '"':
+		return qr.literal()
+	}
+
+	return rdfTerm{}, qr.error("unexpected " + strconv.Quote(qr.s[qr.pos:]))
+}
+
+func (qr *quadReader) iri() (string, error) {
+	end := strings.IndexByte(qr.s[qr.pos:], '>')
+	if end < 0 {
+		return "", qr.error("unterminated IRI")
+	}
+
+	iri, ok := unescape(qr.s[qr.pos+1 : qr.pos+end])
+	if !ok {
+		return "", qr.error("invalid escape")
+	}
+	qr.pos += end + 1
+
+	return iri, nil
+}
+
+func (qr *quadReader) literal() (rdfTerm, error) {
+	start := qr.pos + 1
+	end := start
+	for ; end < len(qr.s) && qr.s[end] != '"'; end++ {
+		if qr.s[end] == '\\' {
+			end++
+		}
+	}
+	if end >= len(qr.s) {
+		return rdfTerm{}, qr.error("unterminated string")
+	}
+
+	value, ok := unescape(qr.s[start:end])
+	if !ok {
+		return rdfTerm{}, qr.error("invalid escape")
+	}
+	t := rdfTerm{kind: literalTerm, value: value}
+	qr.pos = end + 1
+
+	var err error
+	switch {
+	case strings.HasPrefix(qr.s[qr.pos:], "^^"):
+		qr.pos += 2
+		if qr.pos == len(qr.s) || qr.s[qr.pos] != '<' {
+			return t, qr.error("expected datatype IRI")
+		}
+		t.datatype, err = qr.iri()
+
+	case strings.HasPrefix(qr.s[qr.pos:], "@"):
+		start := qr.pos + 1
+		end := start
+		for end < len(qr.s) && (isLetter(qr.s[end]) || end > start && (qr.s[end] == '-' || qr.s[end] >= '0' && qr.s[end] <= '9')) {
+			end++
+		}
+		if end == start {
+			return t, qr.error("expected language tag")
+		}
+		t.language = qr.s[start:end]
+		qr.pos = end
+	}
+
+	return t, err
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// unescape replaces the escape sequences allowed in strings and IRIs,
+// returning false if s contains an invalid escape.
+func unescape(s string) (string, bool) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, true
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		if i == len(s) {
+			return "", false
+		}
+
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'b':
+			b.WriteByte('\b')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case '"', '\'', '\\':
+			b.WriteByte(s[i])
+		case 'u', 'U':
+			size := 4
+			if s[i] == 'U' {
+				size = 8
+			}
+			if i+size >= len(s) {
+				return "", false
+			}
+			r, err := strconv.ParseUint(s[i+1:i+1+size], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(r)) {
+				return "", false
+			}
+			b.WriteRune(rune(r))
+			i += size
+		default:
+			return "", false
+		}
+	}
+
+	return b.String(), true
+}
@@ -0,0 +1,194 @@
+package numbersix
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"hawx.me/code/assert"
+)
+
+func sortedLines(s string) []string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	sort.Strings(lines)
+	return lines
+}
+
+// replaceSubject returns the triples with the subject, and any Ref to it,
+// replaced.
+func replaceSubject(triples []Triple, from, to string) []Triple {
+	replaced := make([]Triple, len(triples))
+	for i, triple := range triples {
+		if triple.Subject == from {
+			triple.Subject = to
+		}
+		if triple.v == refPrefix+from {
+			triple.v = refPrefix + to
+		}
+		replaced[i] = triple
+	}
+	return replaced
+}
+
+func TestExportNTriples(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+
+	db.Set("https://example.com/1", "name", "Hello \"world\"\n")
+	db.Set("https://example.com/1", "author", Ref("john doe"))
+	db.Set("https://example.com/1", "http://schema.org/wordCount", 12)
+	db.Set("https://example.com/1", "rating", 4.5)
+	db.Set("https://example.com/1", "published", time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC))
+	db.Set("https://example.com/1", "draft", false)
+	db.Set("https://example.com/1", "tags", []string{"a", "b"})
+	db.Set("john doe", "name", "John")
+
+	var buf bytes.Buffer
+	assert.Nil(db.ExportNTriples(&buf, All()))
+
+	assert.Equal(sortedLines(`
+<https://example.com/1> <urn:numbersix:name> "Hello \"world\"\n" .
+<https://example.com/1> <urn:numbersix:author> _:john_20doe .
+<https://example.com/1> <http://schema.org/wordCount> "12"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<https://example.com/1> <urn:numbersix:rating> "4.5"^^<http://www.w3.org/2001/XMLSchema#decimal> .
+<https://example.com/1> <urn:numbersix:published> "2019-01-02T03:04:05Z"^^<http://www.w3.org/2001/XMLSchema#dateTime> .
+<https://example.com/1> <urn:numbersix:draft> "false"^^<http://www.w3.org/2001/XMLSchema#boolean> .
+<https://example.com/1> <urn:numbersix:tags> "[\"a\",\"b\"]"^^<http://www.w3.org/1999/02/22-rdf-syntax-ns#JSON> .
+_:john_20doe <urn:numbersix:name> "John" .
+`), sortedLines(buf.String()))
+
+	buf.Reset()
+	assert.Nil(db.ExportNQuads(&buf, About("john doe"), "https://example.com/graph"))
+	assert.Equal("_:john_20doe <urn:numbersix:name> \"John\" <https://example.com/graph> .\n", buf.String())
+
+	t.Run("round trip", func(t *testing.T) {
+		other, _ := Open("file::memory:")
+
+		buf.Reset()
+		assert.Nil(db.ExportNTriples(&buf, All()))
+		assert.Nil(other.ImportNTriples(&buf))
+
+		john, _ := other.List(Where("name", "John"))
+		if !assert.Len(john, 1) {
+			return
+		}
+		assert.True(strings.HasPrefix(john[0].Subject, "urn:uuid:"))
+
+		expected, _ := db.List(All())
+		actual, _ := other.List(All())
+		assert.Equal(replaceSubject(expected, "john doe", john[0].Subject), actual)
+	})
+}
+
+func TestImportNTriples(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+
+	err := db.ImportNTriples(strings.NewReader(`# a comment
+<https://example.com/1> <http://purl.org/dc/terms/title> "Bonjour"@fr .
+<https://example.com/1> <urn:numbersix:count> "007"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<https://example.com/1> <urn:numbersix:big> "2"^^<http://www.w3.org/2001/XMLSchema#long> . # another
+
+<https://example.com/1> <urn:numbersix:ok> "1"^^<http://www.w3.org/2001/XMLSchema#boolean> .
+<https://example.com/1> <urn:numbersix:data> "{\"a\": 1}"^^<http://www.w3.org/1999/02/22-rdf-syntax-ns#JSON> .
+<https://example.com/1> <urn:numbersix:other> "x"^^<https://example.com/type> .
+<https://example.com/1> <urn:numbersix:see> _:b0.
+<https://example.com/1> <urn:numbersix:ratio> "1.5e3"^^<http://www.w3.org/2001/XMLSchema#double> .
+_:b0 <urn:numbersix:name> "café\t\U0001F600" .
+`))
+	assert.Nil(err)
+
+	groups, _ := db.Groups(About("https://example.com/1"))
+	if !assert.Len(groups, 1) {
+		return
+	}
+	properties := groups[0].Properties
+	delete(properties, "see")
+
+	assert.Equal(map[string][]interface{}{
+		"http://purl.org/dc/terms/title": {"Bonjour"},
+		"count":                          {float64(7)},
+		"big":                            {float64(2)},
+		"ok":                             {true},
+		"data":                           {map[string]interface{}{"a": float64(1)}},
+		"other":                          {"x"},
+		"ratio":                          {float64(1500)},
+	}, properties)
+
+	ok, _ := db.Any(About("https://example.com/1").Where("count", 7).Where("ratio", 1500))
+	assert.True(ok)
+
+	b0, _ := db.List(Where("name", "café\t😀"))
+	if assert.Len(b0, 1) {
+		assert.True(strings.HasPrefix(b0[0].Subject, "urn:uuid:"))
+
+		ok, _ = db.Any(About("https://example.com/1").Where("see", Ref(b0[0].Subject)))
+		assert.True(ok)
+	}
+
+	t.Run("blank nodes are scoped to the document", func(t *testing.T) {
+		assert.Nil(db.ImportNTriples(strings.NewReader(`_:b0 <urn:numbersix:name> "other" .`)))
+
+		ok, _ := db.Any(Where("name", "other").Where("name", "café\t😀"))
+		assert.False(ok)
+	})
+}
+
+func TestImportNQuads(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+
+	document := `<https://example.com/1> <urn:numbersix:name> "default" .
+<https://example.com/1> <urn:numbersix:name> "named" <https://example.com/graph> .
+<https://example.com/1> <urn:numbersix:name> "blank" _:g .
+`
+
+	assert.Nil(db.ImportNQuads(strings.NewReader(document), "https://example.com/graph"))
+	assert.Nil(db.ImportNQuads(strings.NewReader(document), "g"))
+
+	triples, _ := db.List(All())
+	if assert.Len(triples, 2) {
+		var first, second string
+		triples[0].Value(&first)
+		triples[1].Value(&second)
+		assert.Equal([]string{"blank", "named"}, []string{first, second})
+	}
+
+	assert.Nil(db.ImportNQuads(strings.NewReader(document), ""))
+
+	triples, _ = db.List(All())
+	assert.Len(triples, 3)
+}
+
+func TestImportNTriplesErrors(t *testing.T) {
+	testCases := map[string]string{
+		`<a:b> <a:c> "x"`:               "line 1: expected '.'",
+		`<a:b> <a:c> "x" <a:g> .`:       "line 1: expected '.'",
+		`"x" <a:c> <a:d> .`:             "line 1: subject must be an IRI or blank node",
+		`<a:b> _:c <a:d> .`:             "line 1: predicate must be an IRI",
+		"\n<a:b> <a:c> \"x .":           "line 2: unterminated string",
+		`<a:b> <a:c> "\q" .`:            "line 1: invalid escape",
+		`<a:b> <a:c> "x" . <a:b>`:       `line 1: unexpected "<a:b>"`,
+		`<a:b> <a:c> "x"^^"y" .`:        "line 1: expected datatype IRI",
+		`<a:b> <a:c`:                    "line 1: unterminated IRI",
+		`<a:b> <a:c> "x" . # ok` + "\n": "",
+	}
+
+	for document, expected := range testCases {
+		t.Run(document, func(t *testing.T) {
+			db, _ := Open("file::memory:")
+
+			err := db.ImportNTriples(strings.NewReader(document))
+			if expected == "" {
+				assert.Nil(t, err)
+			} else if assert.NotNil(t, err) {
+				assert.Equal(t, expected, err.Error())
+			}
+		})
+	}
+}
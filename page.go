@@ -0,0 +1,139 @@
+package numbersix
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// A PageToken marks the position of the last subject returned by an ordered
+// query, so that the next page of results can be requested by passing it to
+// Continue.
+type PageToken struct {
+	value   string
+	subject string
+}
+
+// ParsePageToken reads a PageToken from the string returned by its String
+// method.
+func ParsePageToken(s string) (*PageToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []string
+	if err = json.Unmarshal(data, &parts); err != nil {
+		return nil, err
+	}
+	if len(parts) != 2 {
+		return nil, errors.New("page token has wrong number of parts")
+	}
+
+	return &PageToken{value: parts[0], subject: parts[1]}, nil
+}
+
+// String returns an opaque representation of the token, suitable for using in
+// URLs.
+func (t *PageToken) String() string {
+	data, _ := json.Marshal([]string{t.value, t.subject})
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// condition returns the SQL to select subjects that come after the token, when
+// ordered ascending or descending. The subjects are expected in a column named
+// found, with the value they are ordered by in a column named ordering.
+func (t *PageToken) condition(ascending bool) (string, []interface{}) {
+	op := ">"
+	if !ascending {
+		op = "<"
+	}
+
+	var v interface{} = t.value
+	if _, native := typed(t.value); native != nil {
+		v = native
+	}
+
+	return "(ordering " + op + " ? OR (ordering = ? AND found " + op + " ?))",
+		[]interface{}{v, v, t.subject}
+}
+
+// PagedQuery is a Query that returns results a page at a time.
+type PagedQuery interface {
+	Query
+	buildPage(t table) (string, []interface{})
+	next(triples []Triple, value string) *PageToken
+}
+
+// ListPage is the same as List, but also returns a token that can be used to
+// continue the query. If there are no further results the token will be nil.
+func (d *DB) ListPage(query PagedQuery) ([]Triple, *PageToken, error) {
+	return d.store().listPage(query)
+}
+
+func (s store) listPage(query PagedQuery) ([]Triple, *PageToken, error) {
+	qs, args := query.buildPage(s.table())
+
+	rows, err := s.q.QueryContext(s.ctx, qs, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var triples []Triple
+	var value string
+	for rows.Next() {
+		var triple Triple
+		if err := rows.Scan(&triple.Subject, &triple.Predicate, &triple.v, &value); err != nil {
+			return nil, nil, err
+		}
+		triples = append(triples, triple)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return triples, query.next(triples, value), nil
+}
+
+// nextToken returns a token for the last subject in triples, using the value
+// that subject was ordered by, or only the subject if the predicate is empty. If
+// fewer than limit subjects were returned there can be no further pages, so nil
+// is returned.
+func nextToken(predicate string, limit int, triples []Triple, value string) *PageToken {
+	if limit <= 0 || len(triples) == 0 {
+		return nil
+	}
+
+	subjects := 0
+	for i, triple := range triples {
+		if i == 0 || triple.Subject != triples[i-1].Subject {
+			subjects++
+		}
+	}
+	if subjects < limit {
+		return nil
+	}
+
+	last := triples[len(triples)-1].Subject
+	if predicate == "" {
+		return &PageToken{subject: last}
+	}
+
+	return &PageToken{value: value, subject: last}
+}
+
+// limitClause returns the SQL to restrict a query to count results, skipping
+// the first offset.
+func limitClause(count, offset int) (string, []interface{}) {
+	if count <= 0 && offset <= 0 {
+		return "", nil
+	}
+	if count <= 0 {
+		count = -1
+	}
+
+	return "LIMIT ? OFFSET ? ", []interface{}{count, offset}
+}
@@ -0,0 +1,201 @@
+package numbersix
+
+import (
+	"testing"
+	"time"
+
+	"hawx.me/code/assert"
+)
+
+func TestListPage(t *testing.T) {
+	db, _ := Open("file::memory:")
+
+	jan := time.Date(2019, time.January, 1, 12, 0, 0, 0, time.UTC)
+	feb := time.Date(2019, time.February, 1, 12, 0, 0, 0, time.UTC)
+	mar := time.Date(2019, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	db.Set("a", "published", jan)
+	db.Set("b", "published", feb)
+	db.Set("b", "tag", "cool")
+	db.Set("c", "published", feb)
+	db.Set("d", "published", feb)
+	db.Set("d", "tag", "cool")
+	db.Set("e", "published", mar)
+
+	t.Run("Descending", func(t *testing.T) {
+		assert := assert.New(t)
+
+		triples, token, err := db.ListPage(Descending("published").Limit(2))
+		assert.Nil(err)
+		assertTriples(t, triples, []pair{
+			{"e", "published"},
+			{"d", "published"},
+			{"d", "tag"},
+		})
+		if !assert.NotNil(token) {
+			return
+		}
+
+		token, err = ParsePageToken(token.String())
+		assert.Nil(err)
+
+		triples, token, err = db.ListPage(Descending("published").Limit(2).Continue(token))
+		assert.Nil(err)
+		assertTriples(t, triples, []pair{
+			{"c", "published"},
+			{"b", "published"},
+			{"b", "tag"},
+		})
+		if !assert.NotNil(token) {
+			return
+		}
+
+		triples, token, err = db.ListPage(Descending("published").Limit(2).Continue(token))
+		assert.Nil(err)
+		assertTriples(t, triples, []pair{
+			{"a", "published"},
+		})
+		assert.Nil(token)
+	})
+
+	t.Run("After", func(t *testing.T) {
+		assert := assert.New(t)
+
+		triples, token, err := db.ListPage(After("published", jan).Limit(2))
+		assert.Nil(err)
+		assertTriples(t, triples, []pair{
+			{"b", "published"},
+			{"b", "tag"},
+			{"c", "published"},
+		})
+		if !assert.NotNil(token) {
+			return
+		}
+
+		triples, token, err = db.ListPage(After("published", jan).Limit(2).Continue(token))
+		assert.Nil(err)
+		assertTriples(t, triples, []pair{
+			{"d", "published"},
+			{"d", "tag"},
+			{"e", "published"},
+		})
+		if !assert.NotNil(token) {
+			return
+		}
+
+		triples, token, err = db.ListPage(After("published", jan).Limit(2).Continue(token))
+		assert.Nil(err)
+		assert.Len(triples, 0)
+		assert.Nil(token)
+	})
+
+	t.Run("Ascending with Offset", func(t *testing.T) {
+		triples, err := db.List(Ascending("published").Offset(3).Limit(1))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"d", "published"},
+			{"d", "tag"},
+		})
+	})
+
+	t.Run("Before with Offset", func(t *testing.T) {
+		triples, err := db.List(Before("published", mar).Offset(2))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"b", "published"},
+			{"b", "tag"},
+			{"a", "published"},
+		})
+	})
+
+	t.Run("All with Limit and Offset", func(t *testing.T) {
+		triples, err := db.List(All().Limit(2).Offset(1))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"b", "published"},
+			{"b", "tag"},
+			{"c", "published"},
+		})
+	})
+
+	t.Run("Where with Limit and Offset", func(t *testing.T) {
+		triples, err := db.List(Where("published", feb).Without("tag").Limit(1))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"c", "published"},
+		})
+
+		triples, err = db.List(Where("published", feb).Offset(1).Limit(1))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"c", "published"},
+		})
+	})
+}
+
+func TestListPageMultipleValues(t *testing.T) {
+	db, _ := Open("file::memory:")
+
+	db.Set("a", "n", 1)
+	db.Set("b", "n", 2, 9)
+	db.Set("c", "n", 3)
+	db.Set("d", "n", 4)
+
+	t.Run("Ascending", func(t *testing.T) {
+		assert := assert.New(t)
+
+		triples, token, err := db.ListPage(Ascending("n").Limit(2))
+		assert.Nil(err)
+		assertTriples(t, triples, []pair{
+			{"a", "n"},
+			{"b", "n"},
+			{"b", "n"},
+		})
+		if !assert.NotNil(token) {
+			return
+		}
+
+		triples, _, err = db.ListPage(Ascending("n").Limit(2).Continue(token))
+		assert.Nil(err)
+		assertTriples(t, triples, []pair{
+			{"c", "n"},
+			{"d", "n"},
+		})
+	})
+
+	t.Run("Descending", func(t *testing.T) {
+		assert := assert.New(t)
+
+		triples, token, err := db.ListPage(Descending("n").Limit(1))
+		assert.Nil(err)
+		assertTriples(t, triples, []pair{
+			{"b", "n"},
+			{"b", "n"},
+		})
+		if !assert.NotNil(token) {
+			return
+		}
+
+		triples, _, err = db.ListPage(Descending("n").Limit(2).Continue(token))
+		assert.Nil(err)
+		assertTriples(t, triples, []pair{
+			{"d", "n"},
+			{"c", "n"},
+		})
+	})
+}
+
+func TestParsePageTokenInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := ParsePageToken("not a token")
+	assert.NotNil(err)
+
+	_, err = ParsePageToken("W10")
+	assert.NotNil(err)
+}
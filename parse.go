@@ -0,0 +1,620 @@
+package numbersix
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Parse reads a query from its textual form, as returned by the String method
+// of each query. For example:
+//
+//    where post-type = "note" and has photo without deleted order by published desc limit 20
+//
+// A query that selects subjects may begin with "all" or "where", followed by a
+// condition, and then optionally "order by" a predicate, "asc" or "desc",
+// "limit", "offset" and "continue" with a PageToken. Conditions are written as:
+//
+//    predicate = value         predicate != value       predicate ^= value
+//    predicate > value         predicate >= value       predicate < value
+//    predicate <= value        predicate between value and value
+//    predicate in (value, ...) has predicate            without predicate
+//    not condition             condition and condition  condition or condition
+//    (condition)
+//
+// Conditions next to each other are combined with "and", which takes
+// precedence over "or". Values are written as JSON, or as @subject for a Ref.
+// Predicates and subjects may be quoted like a JSON string if they contain
+// spaces or symbols, or are a keyword.
+//
+// A query about a subject is written as:
+//
+//    about subject where condition expand 2
+//
+// And a query following a path, as:
+//
+//    path subject follow in-reply-to, author depth 10 reverse
+//
+// If the query cannot be parsed a *ParseError is returned.
+func Parse(s string) (Query, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	var query Query
+	switch {
+	case p.keyword("about"):
+		query, err = p.about()
+	case p.keyword("path"):
+		query, err = p.path()
+	default:
+		query, err = p.selection()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.unexpected(tok, "end of query")
+	}
+
+	return query, nil
+}
+
+// A ParseError describes why a query could not be parsed, and the offset in
+// bytes where the problem was found.
+type ParseError struct {
+	Offset  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return "parse error at offset " + strconv.Itoa(e.Offset) + ": " + e.Message
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenJSON
+	tokenSymbol
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of query"
+	}
+
+	return strconv.Quote(t.text)
+}
+
+// symbols are the characters that end a word.
+const symbols = `()@,=!^<>"[{`
+
+var operators = []string{"!=", "^=", ">=", "<=", "=", ">", "<", "(", ")", ",", "@"}
+
+// keywords cannot be used as a bare predicate or subject.
+var keywords = map[string]bool{
+	"about": true, "all": true, "and": true, "asc": true, "between": true,
+	"by": true, "continue": true, "depth": true, "desc": true, "expand": true,
+	"follow": true, "has": true, "in": true, "limit": true, "none": true,
+	"not": true, "offset": true, "or": true, "order": true, "path": true,
+	"reverse": true, "where": true, "without": true,
+}
+
+func lex(s string) (tokens []token, err error) {
+	i := 0
+
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '"' || c == '[' || c == '{':
+			end, ok := scanJSON(s, i)
+			if !ok {
+				return nil, &ParseError{Offset: i, Message: "unterminated " + string(c)}
+			}
+			tokens = append(tokens, token{kind: tokenJSON, text: s[i:end], offset: i})
+			i = end
+
+		case strings.IndexByte(symbols, c) >= 0:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, &ParseError{Offset: i, Message: "unexpected " + strconv.Quote(string(c))}
+			}
+			tokens = append(tokens, token{kind: tokenSymbol, text: op, offset: i})
+			i += len(op)
+
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\n\r"+symbols, rune(s[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: s[start:i], offset: start})
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, offset: len(s)}), nil
+}
+
+// scanJSON returns the end of the JSON string, array or object starting at i.
+func scanJSON(s string, i int) (int, bool) {
+	depth := 0
+	inString := false
+
+	for ; i < len(s); i++ {
+		c := s[i]
+
+		if inString {
+			switch c {
+			case '\\':
+				i++
+			case '"':
+				inString = false
+				if depth == 0 {
+					return i + 1, true
+				}
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+			if depth == 0 {
+				return i + 1, true
+			}
+		}
+	}
+
+	return i, false
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword consumes the next token if it is the keyword.
+func (p *parser) keyword(keyword string) bool {
+	if tok := p.peek(); tok.kind == tokenWord && strings.EqualFold(tok.text, keyword) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+// symbol consumes the next token if it is the symbol.
+func (p *parser) symbol(symbol string) bool {
+	if tok := p.peek(); tok.kind == tokenSymbol && tok.text == symbol {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) unexpected(tok token, expected string) error {
+	return &ParseError{Offset: tok.offset, Message: "expected " + expected + ", found " + tok.String()}
+}
+
+// isKeyword returns true if the next token is any keyword.
+func (p *parser) isKeyword() bool {
+	tok := p.peek()
+	return tok.kind == tokenWord && keywords[strings.ToLower(tok.text)]
+}
+
+// word reads a predicate or subject, which is either a bare word that is not a
+// keyword or a JSON string.
+func (p *parser) word(expected string) (string, error) {
+	tok := p.peek()
+
+	switch {
+	case tok.kind == tokenWord && !keywords[strings.ToLower(tok.text)]:
+		p.pos++
+		return tok.text, nil
+
+	case tok.kind == tokenJSON && tok.text[0] == '"':
+		p.pos++
+		var s string
+		if err := json.Unmarshal([]byte(tok.text), &s); err != nil {
+			return "", &ParseError{Offset: tok.offset, Message: "invalid string " + tok.text}
+		}
+		return s, nil
+	}
+
+	return "", p.unexpected(tok, expected)
+}
+
+func (p *parser) number(expected string) (int, error) {
+	tok := p.next()
+
+	n, err := strconv.Atoi(tok.text)
+	if tok.kind != tokenWord || err != nil || n < 0 {
+		return 0, p.unexpected(tok, expected)
+	}
+
+	return n, nil
+}
+
+// value reads a value, which is written as JSON or as @subject for a Ref.
+func (p *parser) value() (interface{}, error) {
+	if p.symbol("@") {
+		subject, err := p.word("subject")
+		return Ref(subject), err
+	}
+
+	tok := p.next()
+	if tok.kind != tokenWord && tok.kind != tokenJSON {
+		return nil, p.unexpected(tok, "value")
+	}
+
+	if !json.Valid([]byte(tok.text)) {
+		return nil, p.unexpected(tok, "value")
+	}
+
+	dec := json.NewDecoder(strings.NewReader(tok.text))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, p.unexpected(tok, "value")
+	}
+
+	// numbers are made canonical, so that 1.0 finds values set as 1
+	return canonical(v), nil
+}
+
+func (p *parser) selection() (*SelectQuery, error) {
+	q := Select()
+
+	if !p.keyword("all") && p.keyword("where") {
+		condition, err := p.condition()
+		if err != nil {
+			return nil, err
+		}
+
+		if and, ok := condition.(andCondition); ok {
+			q.conditions = and
+		} else {
+			q.conditions = []Condition{condition}
+		}
+	}
+
+	if p.keyword("order") {
+		if !p.keyword("by") {
+			return nil, p.unexpected(p.peek(), "by")
+		}
+
+		predicate, err := p.word("predicate")
+		if err != nil {
+			return nil, err
+		}
+
+		q.predicate = predicate
+		q.descending = p.keyword("desc")
+		if !q.descending {
+			p.keyword("asc")
+		}
+	}
+
+	if p.keyword("limit") {
+		n, err := p.number("limit")
+		if err != nil {
+			return nil, err
+		}
+		q.limitCount = n
+	}
+
+	if p.keyword("offset") {
+		n, err := p.number("offset")
+		if err != nil {
+			return nil, err
+		}
+		q.offsetCount = n
+	}
+
+	if p.keyword("continue") {
+		tok := p.peek()
+
+		s, err := p.word("page token")
+		if err != nil {
+			return nil, err
+		}
+
+		token, err := ParsePageToken(s)
+		if err != nil {
+			return nil, &ParseError{Offset: tok.offset, Message: "invalid page token: " + err.Error()}
+		}
+		q.token = token
+	}
+
+	return q, nil
+}
+
+func (p *parser) about() (*AboutQuery, error) {
+	subject, err := p.word("subject")
+	if err != nil {
+		return nil, err
+	}
+
+	q := About(subject)
+
+	if p.keyword("where") {
+		condition, err := p.condition()
+		if err != nil {
+			return nil, err
+		}
+
+		if and, ok := condition.(andCondition); ok {
+			q.conditions = and
+		} else {
+			q.conditions = []Condition{condition}
+		}
+	}
+
+	if p.keyword("expand") {
+		if q.depth, err = p.number("depth"); err != nil {
+			return nil, err
+		}
+	}
+
+	return q, nil
+}
+
+func (p *parser) path() (*PathQuery, error) {
+	start, err := p.word("subject")
+	if err != nil {
+		return nil, err
+	}
+
+	q := Path(start)
+
+	if p.keyword("follow") {
+		for {
+			predicate, err := p.word("predicate")
+			if err != nil {
+				return nil, err
+			}
+			q.Then(predicate)
+
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+
+	if p.keyword("depth") {
+		if q.maxDepth, err = p.number("depth"); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.keyword("reverse") {
+		q.reverse = true
+	}
+
+	return q, nil
+}
+
+// condition reads conditions separated by "or".
+func (p *parser) condition() (Condition, error) {
+	var conditions []Condition
+
+	for {
+		condition, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+
+		if !p.keyword("or") {
+			return Or(conditions...), nil
+		}
+	}
+}
+
+// and reads conditions separated by "and", or next to each other.
+func (p *parser) and() (Condition, error) {
+	var conditions []Condition
+
+	for {
+		condition, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		if and, ok := condition.(andCondition); ok && len(and) > 0 {
+			conditions = append(conditions, and...)
+		} else {
+			conditions = append(conditions, condition)
+		}
+
+		if p.keyword("and") {
+			continue
+		}
+
+		tok := p.peek()
+		if tok.kind == tokenEOF || (tok.kind == tokenSymbol && tok.text == ")") {
+			break
+		}
+		if p.isKeyword() && !p.startsCondition() {
+			break
+		}
+	}
+
+	return And(conditions...), nil
+}
+
+// startsCondition returns true if the next token is a keyword that begins a
+// condition.
+func (p *parser) startsCondition() bool {
+	switch strings.ToLower(p.peek().text) {
+	case "not", "has", "without", "all", "none":
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) unary() (Condition, error) {
+	switch {
+	case p.keyword("not"):
+		condition, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(condition), nil
+
+	case p.keyword("has"):
+		predicate, err := p.word("predicate")
+		return Has(predicate), err
+
+	case p.keyword("without"):
+		predicate, err := p.word("predicate")
+		return Not(Has(predicate)), err
+
+	case p.keyword("all"):
+		return andCondition{}, nil
+
+	case p.keyword("none"):
+		return orCondition{}, nil
+
+	case p.symbol("("):
+		condition, err := p.condition()
+		if err != nil {
+			return nil, err
+		}
+		if !p.symbol(")") {
+			return nil, p.unexpected(p.peek(), `")"`)
+		}
+		return condition, nil
+	}
+
+	predicate, err := p.word("condition")
+	if err != nil {
+		return nil, err
+	}
+
+	if p.keyword("in") {
+		if !p.symbol("(") {
+			return nil, p.unexpected(p.peek(), `"("`)
+		}
+
+		var values []interface{}
+		for !p.symbol(")") {
+			if len(values) > 0 && !p.symbol(",") {
+				return nil, p.unexpected(p.peek(), `"," or ")"`)
+			}
+
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+
+		if len(values) == 0 {
+			return orCondition{}, nil
+		}
+		return in(predicate, values), nil
+	}
+
+	if p.keyword("between") {
+		low, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword("and") {
+			return nil, p.unexpected(p.peek(), "and")
+		}
+		high, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		return &rangeCondition{predicate: predicate, lower: newBound(low), upper: newBound(high), inclusive: true}, nil
+	}
+
+	tok := p.next()
+	if tok.kind != tokenSymbol {
+		return nil, p.unexpected(tok, "operator")
+	}
+
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok.text {
+	case "=":
+		return Eq(predicate, value), nil
+	case "!=":
+		return Not(Eq(predicate, value)), nil
+	case "^=":
+		return Prefix(predicate, value), nil
+	case ">":
+		return &rangeCondition{predicate: predicate, lower: newBound(value)}, nil
+	case ">=":
+		return &rangeCondition{predicate: predicate, lower: newBound(value), inclusive: true}, nil
+	case "<":
+		return &rangeCondition{predicate: predicate, upper: newBound(value)}, nil
+	case "<=":
+		return &rangeCondition{predicate: predicate, upper: newBound(value), inclusive: true}, nil
+	}
+
+	return nil, p.unexpected(tok, "operator")
+}
+
+// formatWord returns a predicate or subject as it would be written for Parse.
+func formatWord(s string) string {
+	if s == "" || keywords[strings.ToLower(s)] || strings.ContainsAny(s, " \t\n\r"+symbols) {
+		data, _ := json.Marshal(s)
+		return string(data)
+	}
+
+	return s
+}
+
+// formatValue returns a marshaled value as it would be written for Parse.
+func formatValue(v string) string {
+	if strings.HasPrefix(v, refPrefix) {
+		return "@" + formatWord(strings.TrimPrefix(v, refPrefix))
+	}
+
+	return v
+}
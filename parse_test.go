@@ -0,0 +1,138 @@
+package numbersix
+
+import (
+	"testing"
+
+	"hawx.me/code/assert"
+)
+
+func TestParse(t *testing.T) {
+	db, _ := Open("file::memory:")
+
+	db.Set("1", "post-type", "note")
+	db.Set("1", "published", 1)
+	db.Set("1", "photo", "a.jpg")
+	db.Set("2", "post-type", "note")
+	db.Set("2", "published", 2)
+	db.Set("2", "photo", "b.jpg")
+	db.Set("2", "deleted", true)
+	db.Set("3", "post-type", "note")
+	db.Set("3", "published", 3)
+	db.Set("3", "photo", "c.jpg")
+	db.Set("3", "author", Ref("john"))
+	db.Set("4", "post-type", "article")
+	db.Set("4", "published", 4)
+	db.Set("john", "name", "John")
+
+	testCases := map[string][]pair{
+		`where post-type = "note" and has photo without deleted order by published desc limit 20`: {
+			{"3", "author"}, {"3", "photo"}, {"3", "post-type"}, {"3", "published"},
+			{"1", "photo"}, {"1", "post-type"}, {"1", "published"},
+		},
+		`where published >= 2 published < 4 or post-type in ("article") order by published`: {
+			{"2", "deleted"}, {"2", "photo"}, {"2", "post-type"}, {"2", "published"},
+			{"3", "author"}, {"3", "photo"}, {"3", "post-type"}, {"3", "published"},
+			{"4", "post-type"}, {"4", "published"},
+		},
+		`WHERE not (photo ^= "a" or photo = "b.jpg") AND published between 1 and 10`: {
+			{"3", "author"}, {"3", "photo"}, {"3", "post-type"}, {"3", "published"},
+			{"4", "post-type"}, {"4", "published"},
+		},
+		`where author = @john`: {
+			{"3", "author"}, {"3", "photo"}, {"3", "post-type"}, {"3", "published"},
+		},
+		`where published = 2.0 or published = 3e0`: {
+			{"2", "deleted"}, {"2", "photo"}, {"2", "post-type"}, {"2", "published"},
+			{"3", "author"}, {"3", "photo"}, {"3", "post-type"}, {"3", "published"},
+		},
+		`all limit 1 offset 3`: {
+			{"4", "post-type"}, {"4", "published"},
+		},
+		`about "3" where author.name = "John" expand 1`: {
+			{"3", "author"}, {"3", "photo"}, {"3", "post-type"}, {"3", "published"},
+			{"john", "name"},
+		},
+		`path "3" follow author`: {
+			{"john", "name"},
+		},
+	}
+
+	for input, expected := range testCases {
+		t.Run(input, func(t *testing.T) {
+			query, err := Parse(input)
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			triples, err := db.Flatten().List(query)
+			assert.Nil(t, err)
+			assertTriples(t, triples, expected)
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	testCases := map[string]struct {
+		offset  int
+		message string
+	}{
+		`where post-type = note`:           {18, `expected value, found "note"`},
+		`where post-type "note"`:           {16, `expected operator, found "\"note\""`},
+		`where (has photo`:                 {16, `expected ")", found end of query`},
+		`where has photo order published`:  {22, `expected by, found "published"`},
+		`where has photo limit ten`:        {22, `expected limit, found "ten"`},
+		`where name = "John`:               {13, `unterminated "`},
+		`where has photo tag in ("a" "b")`: {28, `expected "," or ")", found "\"b\""`},
+		`about where has photo`:            {6, `expected subject, found "where"`},
+		`where has photo ) limit 2`:        {16, `expected end of query, found ")"`},
+	}
+
+	for input, expected := range testCases {
+		t.Run(input, func(t *testing.T) {
+			_, err := Parse(input)
+
+			parseErr, ok := err.(*ParseError)
+			if assert.True(t, ok) {
+				assert.Equal(t, expected.offset, parseErr.Offset)
+				assert.Equal(t, expected.message, parseErr.Message)
+			}
+		})
+	}
+}
+
+func TestQueryString(t *testing.T) {
+	token := &PageToken{value: "2", subject: "b"}
+
+	testCases := map[string]interface {
+		Query
+		String() string
+	}{
+		`all`:                                                All(),
+		`limit 2 offset 1`:                                   All().Limit(2).Offset(1),
+		`about a`:                                            About("a"),
+		`about "a b" where age = 1 expand 2`:                 About("a b").Where("age", 1).Expand(2),
+		`where name = "John" and has age`:                    Where("name", "John").Has("age"),
+		`where name ^= "J" and without deleted`:              Begins("name", "J").Without("deleted"),
+		`where tag in ("a", "b") and x != 1`:                 WhereIn("tag", "a", "b").WhereNot("x", 1),
+		`where a = 1 or b = @john`:                           AnyOf(Eq("a", 1), Eq("b", Ref("john"))),
+		`where age > 22 order by age limit 2`:                After("age", 22).Limit(2),
+		`where age < 22 order by age desc`:                   Before("age", 22),
+		`where age between 1 and 5 order by age`:             Between("age", 1, 5),
+		`where age > 1 and age < 5 order by age`:             Between("age", 1, 5).Exclusive(),
+		`order by "order" desc continue ` + token.String():   Descending("order").Continue(token),
+		`where not (a = 1 or has b) and (c = 2 or d = 3)`:    Select(Not(Or(Eq("a", 1), Has("b"))), Or(Eq("c", 2), Eq("d", 3))),
+		`where none and all`:                                 Select(Or(), And()),
+		`path c follow in-reply-to, author depth 10 reverse`: Path("c", "in-reply-to").Then("author").MaxDepth(10).Reverse(),
+	}
+
+	for expected, query := range testCases {
+		t.Run(expected, func(t *testing.T) {
+			assert.Equal(t, expected, query.String())
+
+			parsed, err := Parse(expected)
+			if assert.Nil(t, err) {
+				assert.Equal(t, expected, parsed.(interface{ String() string }).String())
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package numbersix
+
+import (
+	"strconv"
+	"strings"
+)
+
+type PathQuery struct {
+	start      string
+	predicates []string
+	maxDepth   int
+	reverse    bool
+}
+
+// Path is a query that returns triples for the subjects reached by following
+// the predicates, in order, from the start subject. Only values that are a Ref
+// are followed. For example, if we had triples:
+//
+//    ("c", "in-reply-to", Ref("b"))
+//    ("b", "in-reply-to", Ref("a"))
+//    ("b", "author", Ref("john"))
+//    ("john", "name", "John")
+//
+// Then a query of Path("c", "in-reply-to", "author") would give us triples:
+//
+//    ("john", "name", "John")
+//
+// Use MaxDepth to follow the predicates repeatedly, and Reverse to follow them
+// from referenced subject to referencing subject.
+func Path(start string, predicates ...string) *PathQuery {
+	return &PathQuery{
+		start:      start,
+		predicates: predicates,
+		maxDepth:   1,
+	}
+}
+
+// Then adds a predicate to the end of the path to follow.
+func (q *PathQuery) Then(predicate string) *PathQuery {
+	q.predicates = append(q.predicates, predicate)
+	return q
+}
+
+// MaxDepth changes the query so that the path is followed up to depth times,
+// returning the subjects reached at the end of each. The triples are returned
+// ordered by the number of times the path was followed to reach the subject. A
+// subject is not followed if it has already been visited, so cycles will not be
+// followed indefinitely.
+//
+// For example, Path("c", "in-reply-to").MaxDepth(10) would return the triples
+// for "b" and "a", each post that "c" is a reply to.
+func (q *PathQuery) MaxDepth(depth int) *PathQuery {
+	q.maxDepth = depth
+	return q
+}
+
+// Reverse changes the query so that the predicates are followed backwards, from
+// the subject that is referenced to the subject that has the reference.
+//
+// For example, Path("a", "in-reply-to").Reverse().MaxDepth(10) would return
+// the triples for "b" and "c", each post replying to "a" or its replies.
+func (q *PathQuery) Reverse() *PathQuery {
+	q.reverse = true
+	return q
+}
+
+// String returns the query as it would be written for Parse.
+func (q *PathQuery) String() string {
+	s := "path " + formatWord(q.start)
+
+	if len(q.predicates) > 0 {
+		predicates := make([]string, len(q.predicates))
+		for i, predicate := range q.predicates {
+			predicates[i] = formatWord(predicate)
+		}
+		s += " follow " + strings.Join(predicates, ", ")
+	}
+
+	if q.maxDepth != 1 {
+		s += " depth " + strconv.Itoa(q.maxDepth)
+	}
+
+	if q.reverse {
+		s += " reverse"
+	}
+
+	return s
+}
+
+// separator delimits the subjects visited on a path.
+const separator = "char(31)"
+
+func (q *PathQuery) build(t table) (qs string, args []interface{}) {
+	if len(q.predicates) == 0 || q.maxDepth <= 0 {
+		return "SELECT subject, predicate, value FROM " + t.name + " WHERE 0", nil
+	}
+
+	steps := strconv.Itoa(len(q.predicates))
+	refStart := strconv.Itoa(len(refPrefix) + 1)
+
+	next, join := "substr(value, "+refStart+")", "subject = walk.found"
+	if q.reverse {
+		next, join = "subject", "value = '"+refPrefix+"' || walk.found"
+	}
+
+	predicate := "?"
+	if len(q.predicates) > 1 {
+		predicate = "CASE walk.step"
+		for i := range q.predicates {
+			predicate += " WHEN " + strconv.Itoa(i) + " THEN ?"
+		}
+		predicate += " END"
+	}
+
+	qs = "WITH RECURSIVE walk(found, step, depth, visited) AS ( " +
+		"SELECT ?, 0, 0, " + separator + " || ? || " + separator +
+		" UNION ALL SELECT " + next + ", (walk.step + 1) % " + steps +
+		", walk.depth + (walk.step + 1) / " + steps +
+		", walk.visited || " + next + " || " + separator +
+		" FROM " + t.name + " INNER JOIN walk ON " + join +
+		" WHERE value_type = ? AND predicate = " + predicate +
+		" AND (walk.step > 0 OR walk.depth < ?)" +
+		" AND instr(walk.visited, " + separator + " || " + next + " || " + separator + ") = 0 ) " +
+		"SELECT subject, predicate, value FROM " + t.name +
+		" INNER JOIN ( SELECT found, MIN(depth) AS depth FROM walk WHERE step = 0 AND depth > 0 GROUP BY found ) AS reached" +
+		" ON subject = reached.found ORDER BY reached.depth, subject, predicate"
+
+	args = append(args, q.start, q.start, typeRef)
+	for _, p := range q.predicates {
+		args = append(args, p)
+	}
+	args = append(args, q.maxDepth)
+
+	return
+}
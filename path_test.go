@@ -0,0 +1,92 @@
+package numbersix
+
+import (
+	"testing"
+
+	"hawx.me/code/assert"
+)
+
+func TestListPath(t *testing.T) {
+	db, _ := Open("file::memory:")
+
+	db.Set("a", "author", Ref("john"))
+	db.Set("b", "in-reply-to", Ref("a"))
+	db.Set("b", "author", Ref("jane"))
+	db.Set("c", "in-reply-to", Ref("b"))
+	db.Set("c", "author", Ref("john"))
+	db.Set("d", "in-reply-to", Ref("a"))
+	db.Set("e", "in-reply-to", "c")
+	db.Set("john", "name", "John")
+	db.Set("jane", "name", "Jane")
+
+	// a cycle
+	db.Set("x", "in-reply-to", Ref("y"))
+	db.Set("y", "in-reply-to", Ref("x"))
+
+	t.Run("single predicate", func(t *testing.T) {
+		triples, err := db.List(Path("c", "in-reply-to"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"b", "author"},
+			{"b", "in-reply-to"},
+		})
+	})
+
+	t.Run("chain of predicates", func(t *testing.T) {
+		triples, err := db.List(Path("c", "in-reply-to").Then("author"))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"jane", "name"},
+		})
+	})
+
+	t.Run("MaxDepth", func(t *testing.T) {
+		triples, err := db.List(Path("c", "in-reply-to").MaxDepth(10))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"b", "author"},
+			{"b", "in-reply-to"},
+			{"a", "author"},
+		})
+	})
+
+	t.Run("MaxDepth with chain", func(t *testing.T) {
+		triples, err := db.List(Path("c", "in-reply-to", "in-reply-to").MaxDepth(2))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"a", "author"},
+		})
+	})
+
+	t.Run("Reverse", func(t *testing.T) {
+		triples, err := db.List(Path("a", "in-reply-to").Reverse().MaxDepth(10))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"b", "author"},
+			{"b", "in-reply-to"},
+			{"d", "in-reply-to"},
+			{"c", "author"},
+			{"c", "in-reply-to"},
+		})
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		triples, err := db.List(Path("x", "in-reply-to").MaxDepth(100))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"y", "in-reply-to"},
+		})
+	})
+
+	t.Run("no predicates", func(t *testing.T) {
+		triples, err := db.List(Path("c"))
+		assert.Nil(t, err)
+		assert.Len(t, triples, 0)
+	})
+}
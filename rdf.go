@@ -0,0 +1,92 @@
+package numbersix
+
+import (
+	"hawx.me/code/numbersix/internal/rdf"
+)
+
+// Namespace is the IRI that predicates are placed in when written as RDF, if
+// they are not already IRIs. So the predicate "name" is written as
+// "urn:numbersix:name", and when read back is stored as "name" again.
+const Namespace = rdf.Namespace
+
+type rdfKind = rdf.Kind
+
+const (
+	iriTerm     = rdf.KindIRI
+	blankTerm   = rdf.KindBlank
+	literalTerm = rdf.KindLiteral
+)
+
+// rdfTerm is a subject, predicate or object as it appears in RDF. For an IRI the
+// value is the IRI, for a blank node it is the label, and for a literal it is the
+// lexical form.
+type rdfTerm struct {
+	kind     rdfKind
+	value    string
+	datatype string
+	language string
+}
+
+// subjectTerm returns the term for a subject, as described by rdf.Subject.
+func subjectTerm(subject string) rdfTerm {
+	kind, value := rdf.Subject(subject)
+
+	return rdfTerm{kind: kind, value: value}
+}
+
+// predicateTerm returns the term for a predicate, placing it in Namespace if it
+// is not an IRI.
+func predicateTerm(predicate string) rdfTerm {
+	return rdfTerm{kind: iriTerm, value: rdf.PredicateIRI(predicate)}
+}
+
+// objectTerm returns the term for a marshaled value, as described by
+// rdf.Object.
+func objectTerm(v string) rdfTerm {
+	kind, value, datatype := rdf.Object(v)
+
+	return rdfTerm{kind: kind, value: value, datatype: datatype}
+}
+
+// subject returns the subject that the term names, the reverse of subjectTerm.
+func (t rdfTerm) subject() string {
+	if t.kind == blankTerm {
+		return rdf.UnblankLabel(t.value)
+	}
+
+	return t.value
+}
+
+// predicate returns the predicate that the term names, the reverse of
+// predicateTerm.
+func (t rdfTerm) predicate() string {
+	return rdf.Predicate(t.value)
+}
+
+// object returns the value to store for the term, the reverse of objectTerm.
+func (t rdfTerm) object() interface{} {
+	if t.kind != literalTerm {
+		return Ref(t.subject())
+	}
+
+	return rdf.Literal(t.value, t.datatype)
+}
+
+// blankNodes gives each blank node label read from a document its own
+// generated subject, as described by rdf.BlankNodes.
+type blankNodes rdf.BlankNodes
+
+// resolve returns the term with a blank node replaced by the IRI generated for
+// its label.
+func (b blankNodes) resolve(t rdfTerm) (rdfTerm, error) {
+	if t.kind != blankTerm {
+		return t, nil
+	}
+
+	subject, err := rdf.BlankNodes(b).Subject(t.value)
+	if err != nil {
+		return t, err
+	}
+
+	return rdfTerm{kind: iriTerm, value: subject}, nil
+}
@@ -0,0 +1,18 @@
+package numbersix
+
+// Referrers returns the triples that have a Ref to the subject as their value,
+// so the subject and predicate of each triple says which subjects reference the
+// subject and how.
+func (d *DB) Referrers(subject string) ([]Triple, error) {
+	return d.store().list(referrersQuery{subject: subject})
+}
+
+type referrersQuery struct {
+	subject string
+}
+
+func (q referrersQuery) build(t table) (string, []interface{}) {
+	v, _ := marshal(Ref(q.subject))
+
+	return "SELECT subject, predicate, value FROM " + t.name + " WHERE value = ? ORDER BY subject, predicate", []interface{}{v}
+}
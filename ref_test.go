@@ -0,0 +1,115 @@
+package numbersix
+
+import (
+	"testing"
+
+	"hawx.me/code/assert"
+)
+
+func TestRef(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	assert.Nil(db.Set("post", "author", Ref("john")))
+	assert.Nil(db.Set("post", "tag", "john"))
+
+	ok, err := db.Any(About("post").Where("author", Ref("john")))
+	assert.Nil(err)
+	assert.True(ok)
+
+	ok, err = db.Any(About("post").Where("author", "john"))
+	assert.Nil(err)
+	assert.False(ok)
+
+	triples, err := db.List(About("post"))
+	assert.Nil(err)
+	if assert.Len(triples, 2) {
+		var r Ref
+		assert.Nil(triples[0].Value(&r))
+		assert.Equal(Ref("john"), r)
+
+		var s string
+		assert.Nil(triples[0].Value(&s))
+		assert.Equal("john", s)
+
+		var i int
+		assert.NotNil(triples[0].Value(&i))
+	}
+}
+
+func TestReferrers(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	assert.Nil(db.Set("post-1", "author", Ref("john")))
+	assert.Nil(db.Set("post-2", "author", Ref("john")))
+	assert.Nil(db.Set("post-2", "like-of", Ref("post-1")))
+	assert.Nil(db.Set("post-3", "author", Ref("jane")))
+	assert.Nil(db.Set("post-3", "mentions", "john"))
+
+	triples, err := db.Referrers("john")
+	assert.Nil(err)
+	assertTriples(t, triples, []pair{
+		{"post-1", "author"},
+		{"post-2", "author"},
+	})
+
+	triples, err = db.Referrers("post-1")
+	assert.Nil(err)
+	assertTriples(t, triples, []pair{
+		{"post-2", "like-of"},
+	})
+}
+
+func TestListAboutExpand(t *testing.T) {
+	db, _ := Open("file::memory:")
+
+	db.Set("post", "content", "hello")
+	db.Set("post", "author", Ref("john"))
+	db.Set("post", "in-reply-to", Ref("other"))
+	db.Set("john", "name", "John")
+	db.Set("john", "org", Ref("example"))
+	db.Set("example", "name", "Example Inc.")
+	db.Set("example", "member", Ref("john"))
+	db.Set("other", "content", "hi")
+
+	t.Run("depth 1", func(t *testing.T) {
+		triples, err := db.List(About("post").Expand(1))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"post", "author"},
+			{"post", "content"},
+			{"post", "in-reply-to"},
+			{"john", "name"},
+			{"john", "org"},
+			{"other", "content"},
+		})
+	})
+
+	t.Run("depth 3 with cycle", func(t *testing.T) {
+		triples, err := db.List(About("post").Expand(3))
+		assert.Nil(t, err)
+
+		assertTriples(t, triples, []pair{
+			{"post", "author"},
+			{"post", "content"},
+			{"post", "in-reply-to"},
+			{"john", "name"},
+			{"john", "org"},
+			{"other", "content"},
+			{"example", "member"},
+			{"example", "name"},
+		})
+	})
+
+	t.Run("with Where", func(t *testing.T) {
+		triples, err := db.List(About("post").Where("content", "nope").Expand(1))
+		assert.Nil(t, err)
+		assert.Len(t, triples, 0)
+
+		triples, err = db.List(About("post").Where("content", "hello").Expand(1))
+		assert.Nil(t, err)
+		assert.Len(t, triples, 6)
+	})
+}
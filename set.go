@@ -12,21 +12,70 @@ func (d *DB) Set(subject, predicate string, value interface{}, more ...interface
 		return d.SetMany(subject, predicate, append(more, value))
 	}
 
+	return d.store().set(subject, predicate, value)
+}
+
+// SetMany is the same as Set, but takes a slice of values to set.
+func (d *DB) SetMany(subject, predicate string, values interface{}) error {
+	return d.Update(func(tx *Tx) error {
+		return tx.SetMany(subject, predicate, values)
+	})
+}
+
+// SetProperties is the same as Set, but takes a map of predicates and values to
+// set.
+func (d *DB) SetProperties(subject string, properties map[string][]interface{}) error {
+	return d.Update(func(tx *Tx) error {
+		return tx.SetProperties(subject, properties)
+	})
+}
+
+// Replace removes all existing values for the subject and predicate, then sets
+// the values given. If no values are given then this is the same as
+// DeletePredicate.
+func (d *DB) Replace(subject, predicate string, values ...interface{}) error {
+	return d.ReplaceProperties(subject, map[string][]interface{}{
+		predicate: values,
+	})
+}
+
+// ReplaceProperties is the same as Replace, but takes a map of predicates and
+// values to set. Predicates for the subject not in the map are not changed.
+func (d *DB) ReplaceProperties(subject string, properties map[string][]interface{}) error {
+	return d.Update(func(tx *Tx) error {
+		return tx.ReplaceProperties(subject, properties)
+	})
+}
+
+func insertQuery(name string) string {
+	return "INSERT OR REPLACE INTO " + name + "(subject, predicate, value, value_type, typed_value) VALUES(?, ?, ?, ?, ?)"
+}
+
+func (s store) set(subject, predicate string, value interface{}) error {
+	if s.flatten {
+		return s.setProperties(subject, map[string][]interface{}{
+			predicate: {value},
+		})
+	}
+
 	v, err := marshal(value)
 	if err != nil {
 		return err
 	}
 
-	_, err = d.db.Exec("INSERT OR REPLACE INTO "+d.name+"(subject, predicate, value) VALUES(?, ?, ?)",
+	kind, native := typed(v)
+
+	_, err = s.q.ExecContext(s.ctx, insertQuery(s.name),
 		subject,
 		predicate,
-		v)
+		v,
+		kind,
+		native)
 
 	return err
 }
 
-// SetMany is the same as Set, but takes a slice of values to set.
-func (d *DB) SetMany(subject, predicate string, values interface{}) error {
+func (s store) setMany(subject, predicate string, values interface{}) error {
 	rv := reflect.ValueOf(values)
 	if rv.Kind() != reflect.Slice {
 		return errors.New("SetMany expected a slice of values")
@@ -35,57 +84,66 @@ func (d *DB) SetMany(subject, predicate string, values interface{}) error {
 		return nil
 	}
 
-	tx, err := d.db.Begin()
-	if err != nil {
-		return err
-	}
-	stmt, err := tx.Prepare("INSERT OR REPLACE INTO " + d.name + "(subject, predicate, value) VALUES(?, ?, ?)")
-	if err != nil {
-		return err
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
 	}
 
-	for i := 0; i < rv.Len(); i++ {
-		v, _ := marshal(rv.Index(i).Interface())
-		_, err = stmt.Exec(subject, predicate, v)
+	return s.setProperties(subject, map[string][]interface{}{
+		predicate: list,
+	})
+}
+
+func (s store) setProperties(subject string, properties map[string][]interface{}) error {
+	if !s.flatten {
+		return s.insertProperties(subject, properties)
 	}
 
+	subjects, err := flatten(subject, properties)
 	if err != nil {
-		terr := tx.Rollback()
-		if terr != nil {
-			return terr
-		}
 		return err
 	}
 
-	return tx.Commit()
+	for subject, properties := range subjects {
+		if err := s.insertProperties(subject, properties); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-// SetProperties is the same as Set, but takes a map of predicates and values to
-// set.
-func (d *DB) SetProperties(subject string, properties map[string][]interface{}) error {
-	tx, err := d.db.Begin()
-	if err != nil {
-		return err
-	}
-	stmt, err := tx.Prepare("INSERT OR REPLACE INTO " + d.name + "(subject, predicate, value) VALUES(?, ?, ?)")
+func (s store) insertProperties(subject string, properties map[string][]interface{}) error {
+	stmt, err := s.q.PrepareContext(s.ctx, insertQuery(s.name))
 	if err != nil {
 		return err
 	}
+	defer stmt.Close()
 
 	for predicate, values := range properties {
 		for _, value := range values {
-			v, _ := marshal(value)
-			_, err = stmt.Exec(subject, predicate, v)
+			v, err := marshal(value)
+			if err != nil {
+				return err
+			}
+
+			kind, native := typed(v)
+
+			if _, err = stmt.ExecContext(s.ctx, subject, predicate, v, kind, native); err != nil {
+				return err
+			}
 		}
 	}
 
-	if err != nil {
-		terr := tx.Rollback()
-		if terr != nil {
-			return terr
+	return nil
+}
+
+func (s store) replaceProperties(subject string, properties map[string][]interface{}) error {
+	for predicate := range properties {
+		if err := s.deletePredicate(subject, predicate); err != nil {
+			return err
 		}
-		return err
 	}
 
-	return tx.Commit()
+	return s.setProperties(subject, properties)
 }
@@ -210,3 +210,60 @@ func TestSetProperties(t *testing.T) {
 		assert.Equal("test", tag)
 	}
 }
+
+func TestReplace(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	assert.Nil(db.Set("thing", "name", "hey"))
+	assert.Nil(db.Set("thing", "tag", "cool"))
+
+	assert.Nil(db.Replace("thing", "name", "hello"))
+	assert.Nil(db.Replace("thing", "name", "hi"))
+
+	triples, err := db.List(About("thing"))
+	assert.Nil(err)
+
+	if assert.Len(triples, 2) {
+		var name string
+		assert.Equal("name", triples[0].Predicate)
+		assert.Nil(triples[0].Value(&name))
+		assert.Equal("hi", name)
+
+		assert.Equal("tag", triples[1].Predicate)
+	}
+
+	assert.Nil(db.Replace("thing", "tag"))
+
+	triples, err = db.List(About("thing"))
+	assert.Nil(err)
+	assert.Len(triples, 1)
+}
+
+func TestReplaceProperties(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	assert.Nil(db.SetProperties("thing", map[string][]interface{}{
+		"name": {"hey"},
+		"size": {1},
+		"tags": {"cool", "test"},
+	}))
+
+	assert.Nil(db.ReplaceProperties("thing", map[string][]interface{}{
+		"name": {"hello"},
+		"tags": {"new", "test"},
+	}))
+
+	triples, err := db.List(About("thing"))
+	assert.Nil(err)
+
+	properties, err := triplesToMap(triples)
+	assert.Nil(err)
+
+	assert.Equal(map[string][]interface{}{
+		"name": {"hello"},
+		"size": {float64(1)},
+		"tags": {"new", "test"},
+	}, properties)
+}
@@ -0,0 +1,399 @@
+package sparql
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"hawx.me/code/numbersix/internal/rdf"
+	"hawx.me/code/numbersix/internal/schema"
+)
+
+// position is where in a triple a variable was bound.
+type position int
+
+const (
+	subjectPosition position = iota
+	predicatePosition
+	objectPosition
+	optionalPosition
+)
+
+// binding is how a variable is referred to in SQL. The term expression gives
+// the value as numbersix would marshal it, so that IRIs are Refs and a predicate
+// is a Ref to the IRI it is written as; the ordering expression gives the value
+// to compare and order by.
+type binding struct {
+	position position
+	column   string
+	term     string
+	ordering string
+}
+
+type scope struct {
+	names    []string
+	bindings map[string]*binding
+}
+
+func newScope() *scope {
+	return &scope{bindings: map[string]*binding{}}
+}
+
+func (s *scope) bind(name string, b *binding) {
+	s.names = append(s.names, name)
+	s.bindings[name] = b
+}
+
+func (s *scope) copy() *scope {
+	c := newScope()
+	for _, name := range s.names {
+		c.bind(name, s.bindings[name])
+	}
+	return c
+}
+
+// builder collects the SQL for a group, keeping the arguments for the FROM and
+// WHERE clauses apart so that they can be given in the right order.
+type builder struct {
+	table   string
+	aliases int
+
+	from      string
+	fromArgs  []interface{}
+	where     []string
+	whereArgs []interface{}
+}
+
+func (b *builder) alias(prefix string) string {
+	b.aliases++
+	return prefix + strconv.Itoa(b.aliases)
+}
+
+func (b *builder) condition(condition string, args ...interface{}) {
+	b.where = append(b.where, condition)
+	b.whereArgs = append(b.whereArgs, args...)
+}
+
+func (b *builder) whereClause() string {
+	if len(b.where) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(b.where, " AND ")
+}
+
+func (q *Query) build(table string) (qs string, args []interface{}, vars []string, err error) {
+	if len(q.where.patterns) == 0 {
+		return "", nil, nil, errors.New("query must contain at least one triple pattern")
+	}
+
+	b := &builder{table: table}
+	s := newScope()
+	if err = b.group(q.where, s, true); err != nil {
+		return
+	}
+
+	vars = q.vars
+	if vars == nil {
+		vars = s.names
+	}
+
+	columns := make([]string, len(vars))
+	for i, name := range vars {
+		if binding, ok := s.bindings[name]; ok {
+			columns[i] = binding.term
+		} else {
+			columns[i] = "NULL"
+		}
+	}
+
+	qs = "SELECT "
+	if q.distinct {
+		qs += "DISTINCT "
+	}
+	qs += strings.Join(columns, ", ") + " FROM " + b.from + b.whereClause()
+	args = append(b.fromArgs, b.whereArgs...)
+
+	if len(q.order) > 0 {
+		orders := make([]string, len(q.order))
+		for i, condition := range q.order {
+			binding, ok := s.bindings[condition.variable]
+			if !ok {
+				return "", nil, nil, errors.New("cannot order by unknown variable ?" + condition.variable)
+			}
+
+			orders[i] = binding.ordering
+			if condition.descending {
+				orders[i] += " DESC"
+			}
+		}
+		qs += " ORDER BY " + strings.Join(orders, ", ")
+	}
+
+	if q.limit >= 0 || q.offset > 0 {
+		qs += " LIMIT ? OFFSET ?"
+		args = append(args, q.limit, q.offset)
+	}
+
+	return
+}
+
+// group adds the triple patterns, optional groups and, if filters is true, the
+// filters of g to the builder, binding the variables in s.
+func (b *builder) group(g group, s *scope, filters bool) error {
+	for i, p := range g.patterns {
+		alias := b.alias("t")
+		if i > 0 {
+			b.from += ", "
+		}
+		b.from += b.table + " AS " + alias
+
+		b.match(s, p.subject, &binding{
+			position: subjectPosition,
+			column:   alias + ".subject",
+			term:     "'" + schema.RefPrefix + "' || " + alias + ".subject",
+			ordering: "'" + schema.RefPrefix + "' || " + alias + ".subject",
+		})
+		b.match(s, p.predicate, &binding{
+			position: predicatePosition,
+			column:   alias + ".predicate",
+			term:     "'" + schema.RefPrefix + "' || " + predicateIRI(alias+".predicate"),
+			ordering: "'" + schema.RefPrefix + "' || " + predicateIRI(alias+".predicate"),
+		})
+		b.match(s, p.object, &binding{
+			position: objectPosition,
+			column:   alias + ".value",
+			term:     alias + ".value",
+			ordering: schema.Ordering(alias),
+		})
+	}
+
+	for _, optional := range g.optionals {
+		if err := b.optional(optional, s); err != nil {
+			return err
+		}
+	}
+
+	if filters {
+		for _, filter := range g.filters {
+			condition, args, err := compileFilter(filter, s)
+			if err != nil {
+				return err
+			}
+			b.condition(condition, args...)
+		}
+	}
+
+	return nil
+}
+
+// predicateIRI returns the SQL for the IRI that the predicate in column is
+// written as, placing it in rdf.Namespace if it does not start with a scheme as
+// rdf.PredicateIRI does.
+func predicateIRI(column string) string {
+	scheme := "substr(" + column + ", 1, instr(" + column + ", ':') - 1)"
+
+	return "CASE WHEN " + scheme + " GLOB '[A-Za-z]*' AND " + scheme + " NOT GLOB '*[^A-Za-z0-9+.-]*'" +
+		" THEN " + column + " ELSE '" + rdf.Namespace + "' || " + column + " END"
+}
+
+// match adds the conditions for a term appearing at the position described by
+// found.
+func (b *builder) match(s *scope, t term, found *binding) {
+	if t.variable == "" {
+		value := t.value
+		if found.position != objectPosition {
+			value = strings.TrimPrefix(value, schema.RefPrefix)
+		}
+		b.condition(found.column+" = ?", value)
+		return
+	}
+
+	existing, ok := s.bindings[t.variable]
+	if !ok {
+		s.bind(t.variable, found)
+		return
+	}
+
+	if existing.position == found.position {
+		b.condition(existing.column + " = " + found.column)
+	} else {
+		b.condition(existing.term + " = " + found.term)
+	}
+}
+
+// optional adds a LEFT JOIN to a subquery for the group, so that its variables
+// are bound when it matches and NULL when it does not.
+func (b *builder) optional(g group, s *scope) error {
+	if len(g.patterns) == 0 {
+		return errors.New("OPTIONAL must contain at least one triple pattern")
+	}
+
+	inner := &builder{table: b.table, aliases: b.aliases}
+	innerScope := newScope()
+	if err := inner.group(g, innerScope, false); err != nil {
+		return err
+	}
+	b.aliases = inner.aliases
+
+	alias := b.alias("o")
+	columns := make([]string, len(innerScope.names))
+	merged := s.copy()
+	var on []string
+
+	for i, name := range innerScope.names {
+		matched := innerScope.bindings[name]
+		column := "v" + strconv.Itoa(i)
+		columns[i] = matched.term + " AS " + column + ", " + matched.ordering + " AS " + column + "_ordering"
+
+		found := &binding{
+			position: optionalPosition,
+			column:   alias + "." + column,
+			term:     alias + "." + column,
+			ordering: alias + "." + column + "_ordering",
+		}
+
+		if existing, ok := s.bindings[name]; ok {
+			on = append(on, existing.term+" = "+found.term)
+		} else {
+			s.bind(name, found)
+			merged.bind(name, found)
+		}
+	}
+
+	var onArgs []interface{}
+	for _, filter := range g.filters {
+		condition, args, err := compileFilter(filter, merged)
+		if err != nil {
+			return err
+		}
+		on = append(on, condition)
+		onArgs = append(onArgs, args...)
+	}
+
+	if len(on) == 0 {
+		on = append(on, "1")
+	}
+
+	b.from += " LEFT JOIN ( SELECT " + strings.Join(columns, ", ") + " FROM " + inner.from + inner.whereClause() +
+		" ) AS " + alias + " ON " + strings.Join(on, " AND ")
+	b.fromArgs = append(b.fromArgs, inner.fromArgs...)
+	b.fromArgs = append(b.fromArgs, inner.whereArgs...)
+	b.fromArgs = append(b.fromArgs, onArgs...)
+
+	return nil
+}
+
+// operand is one side of a comparison in a filter.
+type operand struct {
+	term, ordering string
+	args           []interface{}
+	native         bool
+}
+
+func compileFilter(e expr, s *scope) (string, []interface{}, error) {
+	switch e := e.(type) {
+	case boundExpr:
+		binding, ok := s.bindings[e.variable]
+		if !ok {
+			return "0", nil, nil
+		}
+		return binding.term + " IS NOT NULL", nil, nil
+
+	case notExpr:
+		condition, args, err := compileFilter(e.expr, s)
+		return "NOT (" + condition + ")", args, err
+
+	case binaryExpr:
+		if e.op == "AND" || e.op == "OR" {
+			left, leftArgs, err := compileFilter(e.left, s)
+			if err != nil {
+				return "", nil, err
+			}
+			right, rightArgs, err := compileFilter(e.right, s)
+			if err != nil {
+				return "", nil, err
+			}
+			return "(" + left + " " + e.op + " " + right + ")", append(leftArgs, rightArgs...), nil
+		}
+
+		left, err := compileOperand(e.left, s)
+		if err != nil {
+			return "", nil, err
+		}
+		right, err := compileOperand(e.right, s)
+		if err != nil {
+			return "", nil, err
+		}
+
+		// numbers and times are compared by their typed value, anything else
+		// compares the marshaled value for equality
+		if e.op != "=" && e.op != "!=" || left.native || right.native {
+			return compileComparison(e.op, left, right), append(append(guardArgs(left, right), left.args...), right.args...), nil
+		}
+
+		return "(" + left.term + " " + e.op + " " + right.term + ")", append(left.args, right.args...), nil
+
+	case termExpr:
+		operand, err := compileOperand(e, s)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(" + operand.term + " = 'true')", operand.args, nil
+	}
+
+	return "", nil, errors.New("unsupported filter")
+}
+
+// compileComparison returns the SQL comparing the typed values of left and
+// right. When comparing with a number or time the other side is checked to be
+// one too, as otherwise sqlite would compare text as greater than any number.
+func compileComparison(op string, left, right operand) string {
+	condition := left.ordering + " " + op + " " + right.ordering
+
+	if left.native || right.native {
+		var guards []string
+		for _, o := range []operand{left, right} {
+			if !o.native {
+				guards = append(guards, "typeof("+o.ordering+") IN ('integer', 'real')")
+			}
+		}
+		condition = strings.Join(append(guards, condition), " AND ")
+	}
+
+	return "(" + condition + ")"
+}
+
+// guardArgs returns the arguments for the guards added by compileComparison.
+func guardArgs(left, right operand) (args []interface{}) {
+	if left.native || right.native {
+		for _, o := range []operand{left, right} {
+			if !o.native {
+				args = append(args, o.args...)
+			}
+		}
+	}
+
+	return
+}
+
+func compileOperand(e expr, s *scope) (operand, error) {
+	t, ok := e.(termExpr)
+	if !ok {
+		return operand{}, errors.New("filter comparisons must be between variables and values")
+	}
+
+	if t.term.variable != "" {
+		binding, ok := s.bindings[t.term.variable]
+		if !ok {
+			return operand{}, errors.New("unknown variable ?" + t.term.variable)
+		}
+		return operand{term: binding.term, ordering: binding.ordering}, nil
+	}
+
+	if _, native := schema.Typed(t.term.value); native != nil {
+		return operand{term: "?", ordering: "?", args: []interface{}{native}, native: true}, nil
+	}
+
+	return operand{term: "?", ordering: "?", args: []interface{}{t.term.value}}, nil
+}
@@ -0,0 +1,725 @@
+package sparql
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"hawx.me/code/numbersix/internal/rdf"
+	"hawx.me/code/numbersix/internal/schema"
+)
+
+// A ParseError describes why a query could not be parsed, and the offset in
+// bytes where the problem was found.
+type ParseError struct {
+	Offset  int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return "parse error at offset " + strconv.Itoa(e.Offset) + ": " + e.Message
+}
+
+// A Query is a parsed SPARQL SELECT query.
+type Query struct {
+	vars     []string
+	distinct bool
+	where    group
+	order    []orderCondition
+	limit    int
+	offset   int
+}
+
+type group struct {
+	patterns  []pattern
+	filters   []expr
+	optionals []group
+}
+
+type pattern struct {
+	subject, predicate, object term
+}
+
+// term is either a variable, or a value marshaled as numbersix stores it so an
+// IRI is a Ref and a literal is JSON.
+type term struct {
+	variable string
+	value    string
+}
+
+type orderCondition struct {
+	variable   string
+	descending bool
+}
+
+type expr interface{}
+
+type termExpr struct{ term term }
+
+type boundExpr struct{ variable string }
+
+type notExpr struct{ expr expr }
+
+type binaryExpr struct {
+	op          string
+	left, right expr
+}
+
+// Parse reads a SPARQL SELECT query. Only a subset of SPARQL is supported:
+// PREFIX declarations, SELECT with DISTINCT, basic graph patterns, FILTER with
+// comparisons, &&, ||, ! and bound, OPTIONAL, ORDER BY on variables, LIMIT and
+// OFFSET.
+//
+// IRIs are matched against subjects as written, or as Refs when used as an
+// object. Predicates are matched as ExportNTriples writes them, so the predicate
+// "name" is matched by <urn:numbersix:name>, and for convenience by <name>. A
+// prefixed name that has not been declared is used as written, so mf2:name is
+// the predicate "mf2:name".
+func Parse(s string) (*Query, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, prefixes: map[string]string{}}
+
+	q, err := p.query()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.unexpected(tok, "end of query")
+	}
+
+	return q, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIRI
+	tokenName
+	tokenVar
+	tokenString
+	tokenNumber
+	tokenSymbol
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of query"
+	}
+
+	return strconv.Quote(t.text)
+}
+
+var symbols = []string{"&&", "||", "!=", "<=", ">=", "^^", "=", "<", ">", "!", "{", "}", "(", ")", ".", ";", ",", "*", "@"}
+
+func lex(s string) (tokens []token, err error) {
+	i := 0
+
+	for i < len(s) {
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '#':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+
+		case c == '<' && iriEnd(s, i) > 0:
+			end := iriEnd(s, i)
+			tokens = append(tokens, token{kind: tokenIRI, text: s[i+1 : end-1], offset: i})
+			i = end
+
+		case c == '?' || c == '$':
+			end := i + 1
+			for end < len(s) && isNameChar(s[end]) {
+				end++
+			}
+			if end == i+1 {
+				return nil, &ParseError{Offset: i, Message: "expected variable name"}
+			}
+			tokens = append(tokens, token{kind: tokenVar, text: s[i+1 : end], offset: i})
+			i = end
+
+		case c == '"' || c == '\'':
+			value, end, ok := scanString(s, i)
+			if !ok {
+				return nil, &ParseError{Offset: i, Message: "unterminated string"}
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value, offset: i})
+			i = end
+
+		case isDigit(c) || ((c == '-' || c == '+') && i+1 < len(s) && isDigit(s[i+1])):
+			end := i + 1
+			for end < len(s) && (isDigit(s[end]) || s[end] == '.' || s[end] == 'e' || s[end] == 'E' ||
+				((s[end] == '-' || s[end] == '+') && (s[end-1] == 'e' || s[end-1] == 'E'))) {
+				end++
+			}
+			// a trailing "." ends the triple
+			if s[end-1] == '.' {
+				end--
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: strings.TrimPrefix(s[i:end], "+"), offset: i})
+			i = end
+
+		case isNameChar(c) || c == ':':
+			end := i
+			for end < len(s) && (isNameChar(s[end]) || strings.IndexByte(":.-/%#", s[end]) >= 0) {
+				end++
+			}
+			for s[end-1] == '.' {
+				end--
+			}
+			tokens = append(tokens, token{kind: tokenName, text: s[i:end], offset: i})
+			i = end
+
+		default:
+			symbol := ""
+			for _, sym := range symbols {
+				if strings.HasPrefix(s[i:], sym) {
+					symbol = sym
+					break
+				}
+			}
+			if symbol == "" {
+				return nil, &ParseError{Offset: i, Message: "unexpected " + strconv.Quote(string(c))}
+			}
+			tokens = append(tokens, token{kind: tokenSymbol, text: symbol, offset: i})
+			i += len(symbol)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, offset: len(s)}), nil
+}
+
+// iriEnd returns the end of the IRI starting at i, or 0 if there is not one.
+func iriEnd(s string, i int) int {
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '>':
+			return j + 1
+		case '<', '"', '{', '}', '|', '^', '`', '\\', ' ', '\t', '\n', '\r':
+			return 0
+		}
+	}
+
+	return 0
+}
+
+func scanString(s string, i int) (value string, end int, ok bool) {
+	quote := s[i]
+	var b strings.Builder
+
+	for j := i + 1; j < len(s); j++ {
+		c := s[j]
+
+		switch {
+		case c == quote:
+			return b.String(), j + 1, true
+
+		case c == '\\' && j+1 < len(s):
+			j++
+			switch s[j] {
+			case 't':
+				b.WriteByte('\t')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'u':
+				if j+4 >= len(s) {
+					return "", j, false
+				}
+				r, err := strconv.ParseUint(s[j+1:j+5], 16, 32)
+				if err != nil {
+					return "", j, false
+				}
+				b.WriteRune(rune(r))
+				j += 4
+			default:
+				b.WriteByte(s[j])
+			}
+
+		case c == '\n' || c == '\r':
+			return "", j, false
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", len(s), false
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 0x80 || unicode.IsLetter(rune(c)) || isDigit(c)
+}
+
+type parser struct {
+	tokens   []token
+	pos      int
+	prefixes map[string]string
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword consumes the next token if it is the keyword, ignoring case.
+func (p *parser) keyword(keyword string) bool {
+	if tok := p.peek(); tok.kind == tokenName && strings.EqualFold(tok.text, keyword) {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+// symbol consumes the next token if it is the symbol.
+func (p *parser) symbol(symbol string) bool {
+	if tok := p.peek(); tok.kind == tokenSymbol && tok.text == symbol {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(symbol string) error {
+	if !p.symbol(symbol) {
+		return p.unexpected(p.peek(), strconv.Quote(symbol))
+	}
+
+	return nil
+}
+
+func (p *parser) unexpected(tok token, expected string) error {
+	return &ParseError{Offset: tok.offset, Message: "expected " + expected + ", found " + tok.String()}
+}
+
+func (p *parser) query() (*Query, error) {
+	for p.keyword("PREFIX") {
+		tok := p.next()
+		if tok.kind != tokenName || !strings.HasSuffix(tok.text, ":") {
+			return nil, p.unexpected(tok, "prefix name")
+		}
+
+		iri := p.next()
+		if iri.kind != tokenIRI {
+			return nil, p.unexpected(iri, "IRI")
+		}
+
+		p.prefixes[strings.TrimSuffix(tok.text, ":")] = iri.text
+	}
+
+	if !p.keyword("SELECT") {
+		return nil, p.unexpected(p.peek(), "SELECT")
+	}
+
+	q := &Query{limit: -1}
+	q.distinct = p.keyword("DISTINCT")
+
+	if !p.symbol("*") {
+		for p.peek().kind == tokenVar {
+			q.vars = append(q.vars, p.next().text)
+		}
+
+		if len(q.vars) == 0 {
+			return nil, p.unexpected(p.peek(), "variable or \"*\"")
+		}
+	}
+
+	p.keyword("WHERE")
+
+	where, err := p.group()
+	if err != nil {
+		return nil, err
+	}
+	q.where = where
+
+	if p.keyword("ORDER") {
+		if !p.keyword("BY") {
+			return nil, p.unexpected(p.peek(), "BY")
+		}
+
+		for {
+			condition, ok, err := p.orderCondition()
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				break
+			}
+			q.order = append(q.order, condition)
+		}
+
+		if len(q.order) == 0 {
+			return nil, p.unexpected(p.peek(), "variable")
+		}
+	}
+
+	for {
+		switch {
+		case p.keyword("LIMIT"):
+			if q.limit, err = p.integer(); err != nil {
+				return nil, err
+			}
+		case p.keyword("OFFSET"):
+			if q.offset, err = p.integer(); err != nil {
+				return nil, err
+			}
+		default:
+			return q, nil
+		}
+	}
+}
+
+func (p *parser) integer() (int, error) {
+	tok := p.next()
+
+	n, err := strconv.Atoi(tok.text)
+	if tok.kind != tokenNumber || err != nil || n < 0 {
+		return 0, p.unexpected(tok, "integer")
+	}
+
+	return n, nil
+}
+
+func (p *parser) orderCondition() (orderCondition, bool, error) {
+	if tok := p.peek(); tok.kind == tokenVar {
+		p.next()
+		return orderCondition{variable: tok.text}, true, nil
+	}
+
+	descending := false
+	switch {
+	case p.keyword("ASC"):
+	case p.keyword("DESC"):
+		descending = true
+	default:
+		return orderCondition{}, false, nil
+	}
+
+	if err := p.expect("("); err != nil {
+		return orderCondition{}, false, err
+	}
+
+	tok := p.next()
+	if tok.kind != tokenVar {
+		return orderCondition{}, false, p.unexpected(tok, "variable")
+	}
+
+	if err := p.expect(")"); err != nil {
+		return orderCondition{}, false, err
+	}
+
+	return orderCondition{variable: tok.text, descending: descending}, true, nil
+}
+
+func (p *parser) group() (g group, err error) {
+	if err = p.expect("{"); err != nil {
+		return
+	}
+
+	for !p.symbol("}") {
+		switch {
+		case p.symbol("."):
+
+		case p.keyword("FILTER"):
+			var e expr
+			if p.keyword("bound") {
+				e, err = p.bound()
+			} else {
+				if err = p.expect("("); err != nil {
+					return
+				}
+				if e, err = p.expr(); err != nil {
+					return
+				}
+				err = p.expect(")")
+			}
+			if err != nil {
+				return
+			}
+			g.filters = append(g.filters, e)
+
+		case p.keyword("OPTIONAL"):
+			optional, err := p.group()
+			if err != nil {
+				return g, err
+			}
+			g.optionals = append(g.optionals, optional)
+
+		default:
+			if p.peek().kind == tokenEOF {
+				return g, p.unexpected(p.peek(), "\"}\"")
+			}
+
+			patterns, err := p.triples()
+			if err != nil {
+				return g, err
+			}
+			g.patterns = append(g.patterns, patterns...)
+		}
+	}
+
+	return
+}
+
+// triples reads a subject followed by its predicates and objects.
+func (p *parser) triples() (patterns []pattern, err error) {
+	subject, err := p.term(false)
+	if err != nil {
+		return
+	}
+
+	for {
+		var predicate term
+		if p.keyword("a") {
+			predicate = term{value: rdf.Type}
+		} else if predicate, err = p.term(false); err != nil {
+			return
+		} else if predicate.variable == "" {
+			predicate.value = rdf.Predicate(predicate.value)
+		}
+
+		for {
+			object, err := p.term(true)
+			if err != nil {
+				return nil, err
+			}
+
+			patterns = append(patterns, pattern{subject: subject, predicate: predicate, object: object})
+
+			if !p.symbol(",") {
+				break
+			}
+		}
+
+		if !p.symbol(";") {
+			return
+		}
+
+		// a trailing ";" is allowed
+		if tok := p.peek(); tok.kind == tokenSymbol && (tok.text == "." || tok.text == "}") {
+			return
+		}
+	}
+}
+
+// term reads a variable, an IRI, or if object is true a literal. IRIs in the
+// subject or predicate position are returned as written, and as a Ref in the
+// object position.
+func (p *parser) term(object bool) (term, error) {
+	tok := p.peek()
+
+	switch tok.kind {
+	case tokenVar:
+		p.next()
+		return term{variable: tok.text}, nil
+
+	case tokenIRI, tokenName:
+		iri, err := p.iri()
+		if err != nil {
+			return term{}, err
+		}
+		if !object {
+			return term{value: iri}, nil
+		}
+		if tok.kind == tokenName && (iri == "true" || iri == "false") {
+			return term{value: iri}, nil
+		}
+		return term{value: schema.RefPrefix + iri}, nil
+	}
+
+	if object {
+		return p.literal()
+	}
+
+	return term{}, p.unexpected(tok, "variable or IRI")
+}
+
+// iri reads an IRI or prefixed name.
+func (p *parser) iri() (string, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenIRI:
+		return tok.text, nil
+
+	case tokenName:
+		if i := strings.IndexByte(tok.text, ':'); i >= 0 {
+			if base, ok := p.prefixes[tok.text[:i]]; ok {
+				return base + tok.text[i+1:], nil
+			}
+			return tok.text, nil
+		}
+		if tok.text == "true" || tok.text == "false" {
+			return tok.text, nil
+		}
+	}
+
+	return "", p.unexpected(tok, "IRI")
+}
+
+func (p *parser) literal() (term, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenNumber:
+		var n json.Number
+		if err := json.Unmarshal([]byte(tok.text), &n); err != nil {
+			return term{}, &ParseError{Offset: tok.offset, Message: "invalid number " + tok.String()}
+		}
+		return term{value: n.String()}, nil
+
+	case tokenString:
+		if p.symbol("@") {
+			if lang := p.next(); lang.kind != tokenName {
+				return term{}, p.unexpected(lang, "language tag")
+			}
+		}
+
+		if p.symbol("^^") {
+			datatype, err := p.iri()
+			if err != nil {
+				return term{}, err
+			}
+
+			switch datatype {
+			case rdf.XSDInteger, rdf.XSDDecimal, rdf.XSDDouble, rdf.XSDBoolean, rdf.JSON:
+				if !json.Valid([]byte(tok.text)) {
+					return term{}, &ParseError{Offset: tok.offset, Message: "invalid value for " + datatype}
+				}
+				return term{value: tok.text}, nil
+			}
+		}
+
+		data, _ := json.Marshal(tok.text)
+		return term{value: string(data)}, nil
+	}
+
+	return term{}, p.unexpected(tok, "variable, IRI or literal")
+}
+
+func (p *parser) bound() (expr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	tok := p.next()
+	if tok.kind != tokenVar {
+		return nil, p.unexpected(tok, "variable")
+	}
+
+	return boundExpr{variable: tok.text}, p.expect(")")
+}
+
+func (p *parser) expr() (expr, error) {
+	left, err := p.andExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.symbol("||") {
+		right, err := p.andExpr()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: "OR", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) andExpr() (expr, error) {
+	left, err := p.relationalExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.symbol("&&") {
+		right, err := p.relationalExpr()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op: "AND", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) relationalExpr() (expr, error) {
+	left, err := p.unaryExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	if tok.kind != tokenSymbol {
+		return left, nil
+	}
+
+	switch tok.text {
+	case "=", "!=", "<", ">", "<=", ">=":
+		p.next()
+		right, err := p.unaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		return binaryExpr{op: tok.text, left: left, right: right}, nil
+	}
+
+	return left, nil
+}
+
+func (p *parser) unaryExpr() (expr, error) {
+	switch {
+	case p.symbol("!"):
+		e, err := p.unaryExpr()
+		return notExpr{e}, err
+
+	case p.symbol("("):
+		e, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return e, p.expect(")")
+
+	case p.keyword("bound"):
+		return p.bound()
+	}
+
+	t, err := p.term(true)
+	return termExpr{t}, err
+}
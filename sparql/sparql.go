@@ -0,0 +1,214 @@
+// Package sparql runs SPARQL SELECT queries against a numbersix DB.
+//
+// Triples are seen as the same graph that numbersix.DB.ExportNTriples writes.
+// Subjects, and values stored as a Ref, are IRIs if they are IRIs and blank
+// nodes otherwise. Predicates that are not IRIs are placed in
+// numbersix.Namespace. Any other value is a literal: strings are plain literals,
+// numbers are xsd:integer, xsd:decimal or xsd:double, booleans are xsd:boolean,
+// times are xsd:dateTime, and anything else is rdf:JSON. For example, given the
+// triples:
+//
+//    ("https://example.com/1", "name", "Hello")
+//    ("https://example.com/1", "author", Ref("https://example.com/john"))
+//    ("https://example.com/john", "name", "John")
+//
+// The query
+//
+//    PREFIX : <urn:numbersix:>
+//    SELECT ?post ?author WHERE {
+//      ?post :author ?a .
+//      ?a :name ?author .
+//    }
+//
+// would return the post "https://example.com/1" with the author "John".
+package sparql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"hawx.me/code/numbersix"
+	"hawx.me/code/numbersix/internal/rdf"
+	"hawx.me/code/numbersix/internal/schema"
+)
+
+// Results are the variable bindings found by a query.
+type Results struct {
+	// Vars are the names of the variables selected.
+	Vars []string
+
+	// Bindings has an entry for each solution, mapping the name of each bound
+	// variable to its value.
+	Bindings []map[string]Term
+}
+
+// MarshalJSON encodes the results in the SPARQL 1.1 Query Results JSON Format.
+func (r *Results) MarshalJSON() ([]byte, error) {
+	type head struct {
+		Vars []string `json:"vars"`
+	}
+	type results struct {
+		Bindings []map[string]Term `json:"bindings"`
+	}
+
+	bindings := r.Bindings
+	if bindings == nil {
+		bindings = []map[string]Term{}
+	}
+
+	return json.Marshal(struct {
+		Head    head    `json:"head"`
+		Results results `json:"results"`
+	}{head{r.Vars}, results{bindings}})
+}
+
+// A Term is the value bound to a variable.
+type Term struct {
+	// Type is either "uri", "bnode" or "literal".
+	Type string `json:"type"`
+
+	// Value is the IRI, the blank node label, or the lexical form of the
+	// literal.
+	Value string `json:"value"`
+
+	// Datatype is the IRI of the literal's datatype, it is empty for plain
+	// string literals.
+	Datatype string `json:"datatype,omitempty"`
+}
+
+// newTerm returns the Term for a value as marshaled by numbersix, as described
+// by rdf.Object.
+func newTerm(value string) Term {
+	kind, value, datatype := rdf.Object(value)
+
+	switch kind {
+	case rdf.KindIRI:
+		return Term{Type: "uri", Value: value}
+	case rdf.KindBlank:
+		return Term{Type: "bnode", Value: value}
+	}
+
+	return Term{Type: "literal", Value: value, Datatype: datatype}
+}
+
+// Exec runs the query against db.
+func (q *Query) Exec(db *numbersix.DB) (*Results, error) {
+	table := schema.TableOf(db)
+
+	qs, args, vars, err := q.build(table.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return exec(table, qs, args, vars)
+}
+
+// exec runs the SQL built for a query, binding the columns selected to vars.
+func exec(table schema.Table, qs string, args []interface{}, vars []string) (*Results, error) {
+	rows, err := table.Query(qs, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := &Results{Vars: vars}
+
+	values := make([]sql.NullString, len(vars))
+	dest := make([]interface{}, len(vars))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		binding := map[string]Term{}
+		for i, value := range values {
+			if value.Valid {
+				binding[vars[i]] = newTerm(value.String)
+			}
+		}
+		results.Bindings = append(results.Bindings, binding)
+	}
+
+	return results, rows.Err()
+}
+
+// Exec parses and runs the query against db.
+func Exec(db *numbersix.DB, query string) (*Results, error) {
+	q, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Exec(db)
+}
+
+// Endpoint is a http.Handler implementing the SPARQL 1.1 Protocol for queries,
+// responding with the SPARQL 1.1 Query Results JSON Format.
+type Endpoint struct {
+	// DB is the store that queries are run against.
+	DB *numbersix.DB
+}
+
+func (e *Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var query string
+
+	switch r.Method {
+	case "GET":
+		query = r.FormValue("query")
+
+	case "POST":
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+		if mediaType == "application/sparql-query" {
+			data, err := ioutil.ReadAll(io.LimitReader(r.Body, 1<<20))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			query = string(data)
+		} else {
+			query = r.PostFormValue("query")
+		}
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if query == "" {
+		http.Error(w, "missing query", http.StatusBadRequest)
+		return
+	}
+
+	table := schema.TableOf(e.DB.WithContext(r.Context()))
+
+	q, err := Parse(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	qs, args, vars, err := q.build(table.Name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := exec(table, qs, args, vars)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/sparql-results+json")
+	json.NewEncoder(w).Encode(results)
+}
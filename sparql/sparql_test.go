@@ -0,0 +1,285 @@
+package sparql
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"hawx.me/code/assert"
+	"hawx.me/code/numbersix"
+	"hawx.me/code/numbersix/internal/rdf"
+)
+
+func newDB(t *testing.T) *numbersix.DB {
+	db, err := numbersix.Open("file::memory:")
+	assert.Nil(t, err)
+
+	db.Set("https://example.com/1", "name", "Hello")
+	db.Set("https://example.com/1", "published", "2019-01-01T12:00:00Z")
+	db.Set("https://example.com/1", "author", numbersix.Ref("https://example.com/john"))
+	db.Set("https://example.com/1", "likes", 5)
+	db.Set("https://example.com/2", "name", "Goodbye")
+	db.Set("https://example.com/2", "published", "2019-02-01T12:00:00Z")
+	db.Set("https://example.com/2", "author", numbersix.Ref("https://example.com/jane"))
+	db.Set("https://example.com/2", "likes", 12)
+	db.Set("https://example.com/2", "category", "go")
+	db.Set("https://example.com/john", "name", "John")
+	db.Set("https://example.com/john", "mf2:type", "h-card")
+	db.Set("https://example.com/jane", "name", "Jane")
+
+	return db
+}
+
+func values(results *Results, name string) []string {
+	var vs []string
+	for _, binding := range results.Bindings {
+		vs = append(vs, binding[name].Value)
+	}
+	return vs
+}
+
+func TestExec(t *testing.T) {
+	db := newDB(t)
+
+	t.Run("basic graph pattern", func(t *testing.T) {
+		results, err := Exec(db, `
+      SELECT ?post ?author WHERE {
+        ?post <author> ?a .
+        ?a <name> ?author .
+      }
+      ORDER BY ?author`)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"post", "author"}, results.Vars)
+		assert.Equal(t, []map[string]Term{
+			{
+				"post":   {Type: "uri", Value: "https://example.com/2"},
+				"author": {Type: "literal", Value: "Jane"},
+			},
+			{
+				"post":   {Type: "uri", Value: "https://example.com/1"},
+				"author": {Type: "literal", Value: "John"},
+			},
+		}, results.Bindings)
+	})
+
+	t.Run("prefixes and constants", func(t *testing.T) {
+		results, err := Exec(db, `
+      PREFIX ex: <https://example.com/>
+      SELECT * WHERE { ?post <author> ex:john ; <name> ?name }`)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"post", "name"}, results.Vars)
+		assert.Equal(t, []string{"Hello"}, values(results, "name"))
+	})
+
+	t.Run("undeclared prefix", func(t *testing.T) {
+		results, err := Exec(db, `SELECT ?card WHERE { ?card mf2:type "h-card" }`)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"https://example.com/john"}, values(results, "card"))
+	})
+
+	t.Run("FILTER comparison", func(t *testing.T) {
+		results, err := Exec(db, `
+      SELECT ?post WHERE {
+        ?post <likes> ?likes ; <published> ?published .
+        FILTER (?likes > 10 && ?published >= "2019-01-15T00:00:00+01:00")
+      }`)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"https://example.com/2"}, values(results, "post"))
+	})
+
+	t.Run("FILTER equality", func(t *testing.T) {
+		results, err := Exec(db, `
+      SELECT ?name WHERE {
+        ?post <name> ?name .
+        FILTER (?name != "Hello" && ?post != <https://example.com/john>)
+      }
+      ORDER BY ?name`)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"Goodbye", "Jane"}, values(results, "name"))
+	})
+
+	t.Run("OPTIONAL", func(t *testing.T) {
+		results, err := Exec(db, `
+      SELECT ?post ?category WHERE {
+        ?post <likes> ?likes .
+        OPTIONAL { ?post <category> ?category }
+      }
+      ORDER BY DESC(?likes)`)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []map[string]Term{
+			{
+				"post":     {Type: "uri", Value: "https://example.com/2"},
+				"category": {Type: "literal", Value: "go"},
+			},
+			{
+				"post": {Type: "uri", Value: "https://example.com/1"},
+			},
+		}, results.Bindings)
+	})
+
+	t.Run("OPTIONAL with FILTER bound", func(t *testing.T) {
+		results, err := Exec(db, `
+      SELECT ?post WHERE {
+        ?post <likes> ?likes .
+        OPTIONAL { ?post <category> ?category }
+        FILTER (!bound(?category))
+      }`)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"https://example.com/1"}, values(results, "post"))
+	})
+
+	t.Run("typed values", func(t *testing.T) {
+		results, err := Exec(db, `
+      SELECT ?p ?o WHERE { <https://example.com/1> ?p ?o }
+      ORDER BY ?p`)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []map[string]Term{
+			{
+				"p": {Type: "uri", Value: "urn:numbersix:author"},
+				"o": {Type: "uri", Value: "https://example.com/john"},
+			},
+			{
+				"p": {Type: "uri", Value: "urn:numbersix:likes"},
+				"o": {Type: "literal", Value: "5", Datatype: rdf.XSDInteger},
+			},
+			{
+				"p": {Type: "uri", Value: "urn:numbersix:name"},
+				"o": {Type: "literal", Value: "Hello"},
+			},
+			{
+				"p": {Type: "uri", Value: "urn:numbersix:published"},
+				"o": {Type: "literal", Value: "2019-01-01T12:00:00Z", Datatype: rdf.XSDDateTime},
+			},
+		}, results.Bindings)
+	})
+
+	t.Run("LIMIT and OFFSET", func(t *testing.T) {
+		results, err := Exec(db, `SELECT DISTINCT ?s WHERE { ?s ?p ?o } ORDER BY ?s LIMIT 2 OFFSET 1`)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"https://example.com/2", "https://example.com/jane"}, values(results, "s"))
+	})
+}
+
+func TestExecExportedGraph(t *testing.T) {
+	db, _ := numbersix.Open("file::memory:")
+	db.Set("https://example.com/1", "author", numbersix.Ref("john doe"))
+	db.Set("https://example.com/1", "http://schema.org/about", numbersix.Ref("http://schema.org/author"))
+	db.Set("john doe", "name", "John")
+
+	results, err := Exec(db, `
+    PREFIX : <urn:numbersix:>
+    SELECT ?post ?author ?name WHERE {
+      ?post :author ?author .
+      ?author <urn:numbersix:name> ?name .
+    }`)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]Term{{
+		"post":   {Type: "uri", Value: "https://example.com/1"},
+		"author": {Type: "bnode", Value: "john_20doe"},
+		"name":   {Type: "literal", Value: "John"},
+	}}, results.Bindings)
+
+	// the predicate "author" is urn:numbersix:author, so is not the same as
+	// <http://schema.org/author>, unlike a predicate that is already an IRI
+	results, err = Exec(db, `SELECT ?p WHERE { ?s ?p ?o . ?x <http://schema.org/about> ?p }`)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results.Bindings))
+
+	db.Set("https://example.com/1", "http://schema.org/author", "John")
+
+	results, err = Exec(db, `SELECT DISTINCT ?p WHERE { ?s ?p ?o . ?x <http://schema.org/about> ?p }`)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"http://schema.org/author"}, values(results, "p"))
+}
+
+func TestParseError(t *testing.T) {
+	testCases := map[string]struct {
+		offset  int
+		message string
+	}{
+		`SELECT WHERE { ?s ?p ?o }`:         {7, `expected variable or "*", found "WHERE"`},
+		`SELECT * WHERE { ?s ?p ?o `:        {26, `expected "}", found end of query`},
+		`SELECT * WHERE { ?s "p" ?o }`:      {20, `expected variable or IRI, found "p"`},
+		`SELECT * { ?s ?p ?o FILTER ?o }`:   {27, `expected "(", found "o"`},
+		`SELECT * { ?s ?p ?o } LIMIT ten`:   {28, `expected integer, found "ten"`},
+		`SELECT * { ?s ?p "unterminated }`:  {17, `unterminated string`},
+		`SELECT * { ?s ?p ?o } ORDER BY 5`:  {31, `expected variable, found "5"`},
+		`PREFIX ex <http://a/> SELECT * {}`: {7, `expected prefix name, found "ex"`},
+		`SELECT * { ?s ?p ?o } GROUP BY ?s`: {22, `expected end of query, found "GROUP"`},
+	}
+
+	for input, expected := range testCases {
+		t.Run(input, func(t *testing.T) {
+			_, err := Parse(input)
+
+			parseErr, ok := err.(*ParseError)
+			if assert.True(t, ok) {
+				assert.Equal(t, expected.offset, parseErr.Offset)
+				assert.Equal(t, expected.message, parseErr.Message)
+			}
+		})
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	endpoint := &Endpoint{DB: newDB(t)}
+	query := `SELECT ?name WHERE { <https://example.com/jane> <name> ?name }`
+
+	expected := map[string]interface{}{
+		"head": map[string]interface{}{"vars": []interface{}{"name"}},
+		"results": map[string]interface{}{
+			"bindings": []interface{}{
+				map[string]interface{}{
+					"name": map[string]interface{}{"type": "literal", "value": "Jane"},
+				},
+			},
+		},
+	}
+
+	for name, req := range map[string]*http.Request{
+		"GET":  httptest.NewRequest("GET", "/?"+url.Values{"query": {query}}.Encode(), nil),
+		"form": httptest.NewRequest("POST", "/", strings.NewReader(url.Values{"query": {query}}.Encode())),
+		"body": httptest.NewRequest("POST", "/", strings.NewReader(query)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			switch name {
+			case "form":
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			case "body":
+				req.Header.Set("Content-Type", "application/sparql-query")
+			}
+
+			w := httptest.NewRecorder()
+			endpoint.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "application/sparql-results+json", w.Header().Get("Content-Type"))
+
+			var v map[string]interface{}
+			assert.Nil(t, json.NewDecoder(w.Body).Decode(&v))
+			assert.Equal(t, expected, v)
+		})
+	}
+
+	t.Run("invalid query", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		endpoint.ServeHTTP(w, httptest.NewRequest("GET", "/?"+url.Values{"query": {"SELECT ?x { ?s ?p ?o } ORDER BY ?x"}}.Encode(), nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,297 @@
+package numbersix
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// ErrNotFound is returned by Get when there are no triples for the subject.
+var ErrNotFound = errors.New("subject not found")
+
+// A PutOption changes how Put writes a struct.
+type PutOption func(*putOptions)
+
+type putOptions struct {
+	replace bool
+}
+
+// ReplaceExisting makes Put remove any existing values for each tagged field of
+// the struct, instead of adding to them. This includes fields that have no
+// values to write.
+func ReplaceExisting() PutOption {
+	return func(o *putOptions) {
+		o.replace = true
+	}
+}
+
+// Put writes the fields of the struct v as values for the subject, using the
+// same tags as UnmarshalGroup. A field tagged with the "omitempty" option is not
+// written if it has a zero value, and fields that are nil pointers or empty
+// slices are never written.
+func (d *DB) Put(subject string, v interface{}, opts ...PutOption) error {
+	return d.Update(func(tx *Tx) error {
+		return tx.Put(subject, v, opts...)
+	})
+}
+
+func (s store) put(subject string, v interface{}, opts []PutOption) error {
+	var options putOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	properties, err := marshalProperties(v)
+	if err != nil {
+		return err
+	}
+
+	if options.replace {
+		return s.replaceProperties(subject, properties)
+	}
+
+	return s.setProperties(subject, properties)
+}
+
+// Get finds all triples for the subject and decodes them into v, using
+// UnmarshalGroup.
+func (d *DB) Get(subject string, v interface{}) error {
+	return get(d.List, subject, v)
+}
+
+func get(list func(Query) ([]Triple, error), subject string, v interface{}) error {
+	triples, err := list(About(subject))
+	if err != nil {
+		return err
+	}
+
+	groups := Grouped(triples)
+	if len(groups) == 0 {
+		return ErrNotFound
+	}
+
+	return UnmarshalGroup(groups[0], v)
+}
+
+// UnmarshalGroup decodes the group into the struct pointed to by v. Fields are
+// matched to predicates using the "numbersix" key in the struct field's tag.
+// For example:
+//
+//    type Post struct {
+//      ID        string    `numbersix:",subject"`
+//      Name      string    `numbersix:"name"`
+//      Published time.Time `numbersix:"published"`
+//      Category  []string  `numbersix:"category"`
+//    }
+//
+// Slice fields are given all values for the predicate, other fields are given
+// the first value. A field tagged with the "subject" option is given the
+// subject of the group. Fields that are structs can be decoded from values that
+// are objects, with the object's keys matched to the struct's tags in the same
+// way. Embedded structs without a tag have their fields decoded as if they were
+// part of the outer struct, and fields without a tag are ignored.
+func UnmarshalGroup(group Group, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("UnmarshalGroup expected a pointer to a struct")
+	}
+
+	return unmarshalStruct(group.Subject, group.Properties, rv.Elem())
+}
+
+func parseTag(tag string) (name string, opts []string) {
+	parts := strings.Split(tag, ",")
+
+	return parts[0], parts[1:]
+}
+
+func hasOpt(opts []string, opt string) bool {
+	for _, o := range opts {
+		if o == opt {
+			return true
+		}
+	}
+
+	return false
+}
+
+func unmarshalStruct(subject string, properties map[string][]interface{}, rv reflect.Value) error {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		fv := rv.Field(i)
+
+		tag, ok := field.Tag.Lookup("numbersix")
+		if !ok {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err := unmarshalStruct(subject, properties, fv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if tag == "-" || field.PkgPath != "" {
+			continue
+		}
+
+		name, opts := parseTag(tag)
+		if hasOpt(opts, "subject") {
+			if fv.Kind() != reflect.String {
+				return errors.New("subject field " + field.Name + " must be a string")
+			}
+			fv.SetString(subject)
+			continue
+		}
+
+		values, ok := properties[name]
+		if !ok || len(values) == 0 {
+			continue
+		}
+
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+			for j, value := range values {
+				if err := unmarshalValue(value, slice.Index(j)); err != nil {
+					return err
+				}
+			}
+			fv.Set(slice)
+			continue
+		}
+
+		if err := unmarshalValue(values[0], fv); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unmarshalValue decodes a value, as returned by Triple.Value, into rv.
+func unmarshalValue(value interface{}, rv reflect.Value) error {
+	if rv.Kind() == reflect.Ptr {
+		if value == nil {
+			return nil
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return unmarshalValue(value, rv.Elem())
+	}
+
+	if object, ok := value.(map[string]interface{}); ok && rv.Kind() == reflect.Struct &&
+		!reflect.PtrTo(rv.Type()).Implements(jsonUnmarshalerType) {
+		properties := map[string][]interface{}{}
+		for key, v := range object {
+			if vs, ok := v.([]interface{}); ok {
+				properties[key] = vs
+			} else {
+				properties[key] = []interface{}{v}
+			}
+		}
+
+		return unmarshalStruct("", properties, rv)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, rv.Addr().Interface())
+}
+
+// marshalProperties returns the properties for the struct, or pointer to
+// struct, v. Every tagged field is included in the result, so a field without a
+// value to write will have an empty list of values.
+func marshalProperties(v interface{}) (map[string][]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.New("Put expected a struct")
+	}
+
+	properties := map[string][]interface{}{}
+	marshalStruct(rv, properties)
+
+	return properties, nil
+}
+
+func marshalStruct(rv reflect.Value, properties map[string][]interface{}) {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		fv := rv.Field(i)
+
+		tag, ok := field.Tag.Lookup("numbersix")
+		if !ok {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				marshalStruct(fv, properties)
+			}
+			continue
+		}
+		if tag == "-" || field.PkgPath != "" {
+			continue
+		}
+
+		name, opts := parseTag(tag)
+		if hasOpt(opts, "subject") {
+			continue
+		}
+
+		values := properties[name]
+		if values == nil {
+			values = []interface{}{}
+		}
+
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			for j := 0; j < fv.Len(); j++ {
+				if value, ok := marshalValue(fv.Index(j)); ok {
+					values = append(values, value)
+				}
+			}
+		} else if !hasOpt(opts, "omitempty") || !fv.IsZero() {
+			if value, ok := marshalValue(fv); ok {
+				values = append(values, value)
+			}
+		}
+
+		properties[name] = values
+	}
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// marshalValue returns the value to store for rv, or false if rv is a nil
+// pointer. Structs are converted to objects with keys for each tagged field.
+func marshalValue(rv reflect.Value) (interface{}, bool) {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() == reflect.Struct && !rv.Type().Implements(jsonMarshalerType) &&
+		!reflect.PtrTo(rv.Type()).Implements(jsonMarshalerType) {
+		properties := map[string][]interface{}{}
+		marshalStruct(rv, properties)
+
+		object := map[string]interface{}{}
+		for key, values := range properties {
+			if len(values) > 0 {
+				object[key] = values
+			}
+		}
+		return object, true
+	}
+
+	return rv.Interface(), true
+}
@@ -0,0 +1,177 @@
+package numbersix
+
+import (
+	"testing"
+	"time"
+
+	"hawx.me/code/assert"
+)
+
+func TestUnmarshalGroup(t *testing.T) {
+	assert := assert.New(t)
+
+	type Card struct {
+		Name string `numbersix:"name"`
+		URL  string `numbersix:"url"`
+	}
+
+	type Meta struct {
+		Published time.Time `numbersix:"published"`
+	}
+
+	type Post struct {
+		Meta
+		ID       string   `numbersix:",subject"`
+		Name     string   `numbersix:"name"`
+		Count    int      `numbersix:"count"`
+		Category []string `numbersix:"category"`
+		Author   Card     `numbersix:"author"`
+		Photo    *string  `numbersix:"photo"`
+		Missing  string   `numbersix:"missing"`
+		Ignored  string   `numbersix:"-"`
+		Untagged string
+	}
+
+	published := time.Date(2019, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	group := Group{
+		Subject: "post-1",
+		Properties: map[string][]interface{}{
+			"name":      {"Hello", "Hi"},
+			"count":     {float64(3)},
+			"category":  {"a", "b"},
+			"published": {published.Format(time.RFC3339)},
+			"author": {map[string]interface{}{
+				"name": []interface{}{"John"},
+				"url":  "https://example.com",
+			}},
+			"photo":    {"https://example.com/photo.jpg"},
+			"Untagged": {"nope"},
+			"-":        {"nope"},
+		},
+	}
+
+	var post Post
+	assert.Nil(UnmarshalGroup(group, &post))
+
+	photo := "https://example.com/photo.jpg"
+	assert.Equal(Post{
+		Meta:     Meta{Published: published},
+		ID:       "post-1",
+		Name:     "Hello",
+		Count:    3,
+		Category: []string{"a", "b"},
+		Author:   Card{Name: "John", URL: "https://example.com"},
+		Photo:    &photo,
+	}, post)
+}
+
+func TestUnmarshalGroupWithNonStruct(t *testing.T) {
+	assert := assert.New(t)
+
+	var s string
+	assert.NotNil(UnmarshalGroup(Group{}, &s))
+
+	var post struct{}
+	assert.NotNil(UnmarshalGroup(Group{}, post))
+}
+
+func TestGet(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	assert.Nil(db.Set("john@doe.com", "age", 25))
+	assert.Nil(db.Set("john@doe.com", "nick", "john", "johnny"))
+
+	type Person struct {
+		Email string   `numbersix:",subject"`
+		Age   int      `numbersix:"age"`
+		Nicks []string `numbersix:"nick"`
+	}
+
+	var person Person
+	assert.Nil(db.Get("john@doe.com", &person))
+	assert.Equal(Person{
+		Email: "john@doe.com",
+		Age:   25,
+		Nicks: []string{"john", "johnny"},
+	}, person)
+
+	assert.Equal(ErrNotFound, db.Get("jane@doe.com", &person))
+}
+
+func TestPut(t *testing.T) {
+	assert := assert.New(t)
+
+	type Card struct {
+		Name string `numbersix:"name"`
+		URL  string `numbersix:"url,omitempty"`
+	}
+
+	type Post struct {
+		ID        string    `numbersix:",subject"`
+		Name      string    `numbersix:"name,omitempty"`
+		Content   string    `numbersix:"content"`
+		Published time.Time `numbersix:"published"`
+		Category  []string  `numbersix:"category"`
+		Author    *Card     `numbersix:"author"`
+		Ignored   string    `numbersix:"-"`
+		Untagged  string
+	}
+
+	published := time.Date(2019, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	db, _ := Open("file::memory:")
+	assert.Nil(db.Put("post-1", Post{
+		ID:        "ignored",
+		Content:   "Hello",
+		Published: published,
+		Category:  []string{"a", "b"},
+		Author:    &Card{Name: "John"},
+		Ignored:   "no",
+		Untagged:  "no",
+	}))
+
+	triples, err := db.List(About("post-1"))
+	assert.Nil(err)
+	assertTriples(t, triples, []pair{
+		{"post-1", "author"},
+		{"post-1", "category"},
+		{"post-1", "category"},
+		{"post-1", "content"},
+		{"post-1", "published"},
+	})
+
+	var post Post
+	assert.Nil(db.Get("post-1", &post))
+	assert.Equal(Post{
+		ID:        "post-1",
+		Content:   "Hello",
+		Published: published,
+		Category:  []string{"a", "b"},
+		Author:    &Card{Name: "John"},
+	}, post)
+
+	assert.Nil(db.Put("post-1", &Post{Content: "Bye", Category: []string{"c"}}))
+	assert.Nil(db.Get("post-1", &post))
+	assert.Equal([]string{"a", "b", "c"}, post.Category)
+
+	post = Post{}
+	assert.Nil(db.Put("post-1", &Post{Name: "Title", Content: "Bye", Category: []string{"c"}}, ReplaceExisting()))
+	assert.Nil(db.Get("post-1", &post))
+	assert.Equal(Post{
+		ID:        "post-1",
+		Name:      "Title",
+		Content:   "Bye",
+		Published: time.Time{},
+		Category:  []string{"c"},
+	}, post)
+}
+
+func TestPutWithNonStruct(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	assert.NotNil(db.Put("thing", "hey"))
+	assert.NotNil(db.Put("thing", map[string]string{"a": "b"}))
+}
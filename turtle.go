@@ -0,0 +1,744 @@
+package numbersix
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"hawx.me/code/numbersix/internal/rdf"
+)
+
+// defaultPrefixes are declared by WriteTurtle and ExportTurtle, unless replaced
+// by prefixes of the same name.
+var defaultPrefixes = map[string]string{
+	"":    Namespace,
+	"xsd": rdf.XSDPrefix,
+}
+
+// WriteTurtle writes the groups to w in the Turtle format, mapping terms in the
+// same way as ExportNTriples. Each group is written as a subject followed by its
+// predicates, with the values of a predicate separated by ",". For example:
+//
+//    @prefix : <urn:numbersix:> .
+//    @prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+//
+//    <https://example.com/post> :category "go", "sqlite" ;
+//        :name "Hello" .
+//
+// The prefixes, a map of name to IRI, are declared at the start of the document
+// and used to shorten IRIs that begin with them. The empty prefix is declared
+// for Namespace and "xsd" for XML Schema; these can be replaced by giving a
+// prefix of the same name, or removed by giving it an empty IRI.
+func WriteTurtle(w io.Writer, groups []Group, prefixes map[string]string) error {
+	tw := newTurtleWriter(w, prefixes)
+
+	for _, group := range groups {
+		if err := tw.group(group); err != nil {
+			return err
+		}
+	}
+
+	return tw.w.Flush()
+}
+
+// ExportTurtle writes the triples matching the query to w in the Turtle format,
+// as WriteTurtle does. Triples are read from the database a subject at a time,
+// so the query should return triples ordered by subject.
+func (d *DB) ExportTurtle(w io.Writer, query Query, prefixes map[string]string) error {
+	return d.store().exportTurtle(w, query, prefixes)
+}
+
+// ImportTurtle reads a document in the Turtle format and stores each triple,
+// mapping terms in the same way as ImportNTriples. Blank nodes, whether labelled
+// or anonymous and written with "[]", are given a generated subject, so subjects
+// that are not IRIs are renamed when exported and imported again.
+// Collections are not supported.
+func (d *DB) ImportTurtle(r io.Reader) error {
+	return d.Update(func(tx *Tx) error {
+		return tx.ImportTurtle(r)
+	})
+}
+
+func (s store) exportTurtle(w io.Writer, query Query, prefixes map[string]string) error {
+	cursor, err := s.iterGrouped(query)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+
+	tw := newTurtleWriter(w, prefixes)
+
+	for cursor.Next() {
+		if err := tw.group(cursor.Group()); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	return tw.w.Flush()
+}
+
+func (s store) importTurtle(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	p := &turtleParser{
+		s:        string(data),
+		prefixes: map[string]string{},
+		blanks:   blankNodes{},
+		emit: func(subject, predicate rdfTerm, object interface{}) error {
+			return s.set(subject.subject(), predicate.predicate(), object)
+		},
+	}
+
+	return p.document()
+}
+
+type turtlePrefix struct{ name, iri string }
+
+type turtleWriter struct {
+	w        *bufio.Writer
+	prefixes []turtlePrefix
+	started  bool
+}
+
+func newTurtleWriter(w io.Writer, prefixes map[string]string) *turtleWriter {
+	tw := &turtleWriter{w: bufio.NewWriter(w)}
+
+	for name, iri := range defaultPrefixes {
+		if _, ok := prefixes[name]; !ok {
+			tw.prefixes = append(tw.prefixes, turtlePrefix{name, iri})
+		}
+	}
+	for name, iri := range prefixes {
+		if iri != "" {
+			tw.prefixes = append(tw.prefixes, turtlePrefix{name, iri})
+		}
+	}
+
+	sort.Slice(tw.prefixes, func(i, j int) bool {
+		return tw.prefixes[i].name < tw.prefixes[j].name
+	})
+
+	for _, prefix := range tw.prefixes {
+		tw.w.WriteString("@prefix " + prefix.name + ": <" + escapeIRI(prefix.iri) + "> .\n")
+		tw.started = true
+	}
+
+	return tw
+}
+
+func (tw *turtleWriter) group(group Group) error {
+	if len(group.Properties) == 0 {
+		return nil
+	}
+
+	predicates := make([]string, 0, len(group.Properties))
+	for predicate := range group.Properties {
+		predicates = append(predicates, predicate)
+	}
+	sort.Slice(predicates, func(i, j int) bool {
+		if predicates[i] == rdf.Type || predicates[j] == rdf.Type {
+			return predicates[i] == rdf.Type
+		}
+		return predicates[i] < predicates[j]
+	})
+
+	if tw.started {
+		tw.w.WriteString("\n")
+	}
+	tw.started = true
+
+	tw.w.WriteString(tw.term(subjectTerm(group.Subject)))
+
+	for i, predicate := range predicates {
+		if i > 0 {
+			tw.w.WriteString(" ;\n   ")
+		}
+
+		if predicate == rdf.Type {
+			tw.w.WriteString(" a")
+		} else {
+			tw.w.WriteString(" " + tw.term(predicateTerm(predicate)))
+		}
+
+		for j, value := range group.Properties[predicate] {
+			v, err := marshal(value)
+			if err != nil {
+				return err
+			}
+
+			if j > 0 {
+				tw.w.WriteString(",")
+			}
+			tw.w.WriteString(" " + tw.term(objectTerm(v)))
+		}
+	}
+
+	_, err := tw.w.WriteString(" .\n")
+	return err
+}
+
+// term returns the term as written in Turtle, shortening IRIs using the
+// prefixes and writing numbers and booleans without their datatype where
+// possible.
+func (tw *turtleWriter) term(t rdfTerm) string {
+	switch t.kind {
+	case iriTerm:
+		return tw.iri(t.value)
+
+	case literalTerm:
+		if bareLiteral(t) {
+			return t.value
+		}
+		if t.datatype != "" && t.datatype != rdf.XSDString && t.language == "" {
+			return `"` + literalEscaper.Replace(t.value) + `"^^` + tw.iri(t.datatype)
+		}
+	}
+
+	return t.ntriples()
+}
+
+func (tw *turtleWriter) iri(iri string) string {
+	var best *turtlePrefix
+
+	for i, prefix := range tw.prefixes {
+		if strings.HasPrefix(iri, prefix.iri) && isLocalName(iri[len(prefix.iri):]) &&
+			(best == nil || len(prefix.iri) > len(best.iri)) {
+			best = &tw.prefixes[i]
+		}
+	}
+
+	if best == nil {
+		return "<" + escapeIRI(iri) + ">"
+	}
+
+	return best.name + ":" + iri[len(best.iri):]
+}
+
+// isLocalName returns true if s can be written as the local part of a prefixed
+// name without escaping.
+func isLocalName(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case isLetter(c), c >= '0' && c <= '9', c == '_':
+		case c == '-' && i > 0:
+		case c == '.' && i > 0 && i < len(s)-1:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// bareLiteral returns true if the literal can be written without quotes, so
+// that it is read with the same datatype.
+func bareLiteral(t rdfTerm) bool {
+	switch t.datatype {
+	case rdf.XSDBoolean:
+		return t.value == "true" || t.value == "false"
+	case rdf.XSDInteger, rdf.XSDDecimal, rdf.XSDDouble:
+		datatype, n := scanNumber(t.value)
+		return n == len(t.value) && datatype == t.datatype
+	}
+
+	return false
+}
+
+// scanNumber reads a number written as in Turtle from the start of s, returning
+// its datatype and length, or a length of 0 if s does not start with one.
+func scanNumber(s string) (datatype string, n int) {
+	digits := func() int {
+		start := n
+		for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+			n++
+		}
+		return n - start
+	}
+
+	if n < len(s) && (s[n] == '+' || s[n] == '-') {
+		n++
+	}
+
+	datatype = rdf.XSDInteger
+	count := digits()
+
+	if n+1 < len(s) && s[n] == '.' && s[n+1] >= '0' && s[n+1] <= '9' {
+		n++
+		count += digits()
+		datatype = rdf.XSDDecimal
+	}
+	if count == 0 {
+		return "", 0
+	}
+
+	if n < len(s) && (s[n] == 'e' || s[n] == 'E') {
+		end := n
+		n++
+		if n < len(s) && (s[n] == '+' || s[n] == '-') {
+			n++
+		}
+		if digits() == 0 {
+			return datatype, end
+		}
+		datatype = rdf.XSDDouble
+	}
+
+	return datatype, n
+}
+
+// turtleParser reads a Turtle document, calling emit for each triple.
+type turtleParser struct {
+	s        string
+	pos      int
+	base     *url.URL
+	prefixes map[string]string
+	blanks   blankNodes
+	emit     func(subject, predicate rdfTerm, object interface{}) error
+}
+
+func (p *turtleParser) error(message string) error {
+	return errors.New("line " + strconv.Itoa(strings.Count(p.s[:p.pos], "\n")+1) + ": " + message)
+}
+
+func (p *turtleParser) unexpected() error {
+	if p.pos == len(p.s) {
+		return p.error("unexpected end of document")
+	}
+
+	end := p.pos + 1
+	for end < len(p.s) && !strings.ContainsRune(" \t\r\n", rune(p.s[end])) {
+		end++
+	}
+
+	return p.error("unexpected " + strconv.Quote(p.s[p.pos:end]))
+}
+
+// skip moves past whitespace and comments.
+func (p *turtleParser) skip() {
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '\t', '\r', '\n':
+			p.pos++
+		case '#':
+			for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// consume moves past s if it is next, returning true if it was.
+func (p *turtleParser) consume(s string) bool {
+	p.skip()
+	if strings.HasPrefix(p.s[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+
+	return false
+}
+
+func (p *turtleParser) expect(s string) error {
+	if !p.consume(s) {
+		return p.unexpected()
+	}
+
+	return nil
+}
+
+// keyword moves past the word, ignoring case, if it is next.
+func (p *turtleParser) keyword(word string) bool {
+	p.skip()
+	end := p.pos + len(word)
+	if end > len(p.s) || !strings.EqualFold(p.s[p.pos:end], word) || end < len(p.s) && (isNameChar(p.s[end]) || p.s[end] == ':') {
+		return false
+	}
+
+	p.pos = end
+	return true
+}
+
+func (p *turtleParser) document() error {
+	for {
+		p.skip()
+		if p.pos == len(p.s) {
+			return nil
+		}
+
+		var err error
+		switch {
+		case p.consume("@prefix"):
+			err = p.prefix(true)
+		case p.consume("@base"):
+			err = p.baseIRI(true)
+		case p.keyword("PREFIX"):
+			err = p.prefix(false)
+		case p.keyword("BASE"):
+			err = p.baseIRI(false)
+		default:
+			err = p.triples()
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *turtleParser) prefix(dot bool) error {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.s) && isNameChar(p.s[p.pos]) {
+		p.pos++
+	}
+	name := p.s[start:p.pos]
+	if err := p.expect(":"); err != nil {
+		return err
+	}
+
+	iri, err := p.iriRef()
+	if err != nil {
+		return err
+	}
+	p.prefixes[name] = iri
+
+	if dot {
+		return p.expect(".")
+	}
+	return nil
+}
+
+func (p *turtleParser) baseIRI(dot bool) error {
+	iri, err := p.iriRef()
+	if err != nil {
+		return err
+	}
+
+	if p.base, err = url.Parse(iri); err != nil {
+		return p.error("invalid base IRI")
+	}
+
+	if dot {
+		return p.expect(".")
+	}
+	return nil
+}
+
+func (p *turtleParser) triples() error {
+	p.skip()
+
+	var subject rdfTerm
+	var err error
+
+	if p.consume("[") {
+		if subject, err = p.blankNodePropertyList(); err != nil {
+			return err
+		}
+		if p.consume(".") {
+			return nil
+		}
+	} else if subject, err = p.resource(); err != nil {
+		return err
+	} else if subject, err = p.blanks.resolve(subject); err != nil {
+		return err
+	}
+
+	if err := p.predicateObjectList(subject); err != nil {
+		return err
+	}
+
+	return p.expect(".")
+}
+
+// blankNodePropertyList reads the predicates and objects of an anonymous blank
+// node, after the opening "[", returning the subject generated for it.
+func (p *turtleParser) blankNodePropertyList() (rdfTerm, error) {
+	subject, err := rdf.NewSubject()
+	if err != nil {
+		return rdfTerm{}, err
+	}
+	t := rdfTerm{kind: iriTerm, value: subject}
+
+	if p.consume("]") {
+		return t, nil
+	}
+	if err := p.predicateObjectList(t); err != nil {
+		return t, err
+	}
+
+	return t, p.expect("]")
+}
+
+func (p *turtleParser) predicateObjectList(subject rdfTerm) error {
+	for {
+		predicate, err := p.verb()
+		if err != nil {
+			return err
+		}
+
+		for {
+			object, err := p.object()
+			if err != nil {
+				return err
+			}
+			if err := p.emit(subject, predicate, object); err != nil {
+				return err
+			}
+
+			if !p.consume(",") {
+				break
+			}
+		}
+
+		if !p.consume(";") {
+			return nil
+		}
+		for p.consume(";") {
+		}
+
+		p.skip()
+		if p.pos == len(p.s) || p.s[p.pos] == '.' || p.s[p.pos] == ']' {
+			return nil
+		}
+	}
+}
+
+func (p *turtleParser) verb() (rdfTerm, error) {
+	if p.keyword("a") {
+		return rdfTerm{kind: iriTerm, value: rdf.Type}, nil
+	}
+
+	t, err := p.resource()
+	if err == nil && t.kind != iriTerm {
+		return t, p.error("predicate must be an IRI")
+	}
+
+	return t, err
+}
+
+// resource reads an IRI, prefixed name or blank node label.
+func (p *turtleParser) resource() (rdfTerm, error) {
+	p.skip()
+
+	if p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '<':
+			iri, err := p.iriRef()
+			return rdfTerm{kind: iriTerm, value: iri}, err
+
+		case '(':
+			return rdfTerm{}, p.error("collections are not supported")
+
+		case '_':
+			if strings.HasPrefix(p.s[p.pos:], "_:") {
+				p.pos += 2
+				label := p.name()
+				if label == "" {
+					return rdfTerm{}, p.error("expected blank node label")
+				}
+				return rdfTerm{kind: blankTerm, value: label}, nil
+			}
+		}
+	}
+
+	iri, err := p.prefixedName()
+	return rdfTerm{kind: iriTerm, value: iri}, err
+}
+
+func (p *turtleParser) object() (interface{}, error) {
+	p.skip()
+	if p.pos == len(p.s) {
+		return nil, p.unexpected()
+	}
+
+	switch c := p.s[p.pos]; {
+	case c == '[':
+		p.pos++
+		t, err := p.blankNodePropertyList()
+		return t.object(), err
+
+	case c == '"' || c == '\'':
+		t, err := p.literal()
+		return t.object(), err
+
+	case c == '+' || c == '-' || c == '.' || c >= '0' && c <= '9':
+		datatype, n := scanNumber(p.s[p.pos:])
+		if n == 0 {
+			return nil, p.unexpected()
+		}
+		t := rdfTerm{kind: literalTerm, value: p.s[p.pos : p.pos+n], datatype: datatype}
+		p.pos += n
+		return t.object(), nil
+	}
+
+	if p.keyword("true") {
+		return true, nil
+	}
+	if p.keyword("false") {
+		return false, nil
+	}
+
+	t, err := p.resource()
+	if err != nil {
+		return nil, err
+	}
+	t, err = p.blanks.resolve(t)
+	return t.object(), err
+}
+
+func (p *turtleParser) literal() (rdfTerm, error) {
+	quote := p.s[p.pos : p.pos+1]
+	if strings.HasPrefix(p.s[p.pos:], quote+quote+quote) {
+		quote += quote + quote
+	}
+
+	start := p.pos + len(quote)
+	end := start
+	for ; end < len(p.s) && !strings.HasPrefix(p.s[end:], quote); end++ {
+		if p.s[end] == '\\' {
+			end++
+		} else if len(quote) == 1 && (p.s[end] == '\n' || p.s[end] == '\r') {
+			break
+		}
+	}
+	if end >= len(p.s) || !strings.HasPrefix(p.s[end:], quote) {
+		return rdfTerm{}, p.error("unterminated string")
+	}
+
+	value, ok := unescape(p.s[start:end])
+	if !ok {
+		return rdfTerm{}, p.error("invalid escape")
+	}
+	p.pos = end + len(quote)
+	t := rdfTerm{kind: literalTerm, value: value}
+
+	switch {
+	case strings.HasPrefix(p.s[p.pos:], "^^"):
+		p.pos += 2
+		datatype, err := p.resource()
+		if err == nil && datatype.kind != iriTerm {
+			err = p.error("expected datatype IRI")
+		}
+		t.datatype = datatype.value
+		return t, err
+
+	case strings.HasPrefix(p.s[p.pos:], "@"):
+		p.pos++
+		start := p.pos
+		for p.pos < len(p.s) && (isLetter(p.s[p.pos]) || p.pos > start && (p.s[p.pos] == '-' || p.s[p.pos] >= '0' && p.s[p.pos] <= '9')) {
+			p.pos++
+		}
+		if p.pos == start {
+			return t, p.error("expected language tag")
+		}
+		t.language = p.s[start:p.pos]
+	}
+
+	return t, nil
+}
+
+// iriRef reads an IRI written in angle brackets, resolving it against the base
+// IRI if it is relative.
+func (p *turtleParser) iriRef() (string, error) {
+	p.skip()
+	if p.pos == len(p.s) || p.s[p.pos] != '<' {
+		return "", p.unexpected()
+	}
+
+	end := strings.IndexAny(p.s[p.pos+1:], ">\n")
+	if end < 0 || p.s[p.pos+1+end] != '>' {
+		return "", p.error("unterminated IRI")
+	}
+
+	iri, ok := unescape(p.s[p.pos+1 : p.pos+1+end])
+	if !ok {
+		return "", p.error("invalid escape")
+	}
+	p.pos += end + 2
+
+	if p.base != nil && !rdf.IsIRI(iri) {
+		ref, err := url.Parse(iri)
+		if err != nil {
+			return "", p.error("invalid IRI")
+		}
+		iri = p.base.ResolveReference(ref).String()
+	}
+
+	return iri, nil
+}
+
+func (p *turtleParser) prefixedName() (string, error) {
+	start := p.pos
+	prefix := p.name()
+	if p.pos == len(p.s) || p.s[p.pos] != ':' {
+		p.pos = start
+		return "", p.unexpected()
+	}
+	p.pos++
+
+	iri, ok := p.prefixes[prefix]
+	if !ok {
+		p.pos = start
+		return "", p.error("undefined prefix " + strconv.Quote(prefix))
+	}
+
+	var local strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+
+		if c == '\\' && p.pos+1 < len(p.s) && strings.IndexByte("_~.-!$&'()*+,;=/?#@%", p.s[p.pos+1]) >= 0 {
+			local.WriteByte(p.s[p.pos+1])
+			p.pos += 2
+			continue
+		}
+
+		if !isNameChar(c) && c != ':' && c != '%' {
+			break
+		}
+		// a name can contain "." but not end with it, as that ends the
+		// statement
+		if c == '.' && (p.pos+1 == len(p.s) || !isNameChar(p.s[p.pos+1]) && p.s[p.pos+1] != ':') {
+			break
+		}
+
+		local.WriteByte(c)
+		p.pos++
+	}
+
+	return iri + local.String(), nil
+}
+
+// name reads a prefix or blank node label.
+func (p *turtleParser) name() string {
+	start := p.pos
+	for p.pos < len(p.s) && isNameChar(p.s[p.pos]) {
+		p.pos++
+	}
+	for p.pos > start && p.s[p.pos-1] == '.' {
+		p.pos--
+	}
+
+	return p.s[start:p.pos]
+}
+
+// isNameChar returns true if c can appear in a prefixed name or blank node
+// label. Any byte of a multi-byte character is allowed.
+func isNameChar(c byte) bool {
+	return isLetter(c) || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.' || c >= 0x80
+}
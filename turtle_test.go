@@ -0,0 +1,159 @@
+package numbersix
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"hawx.me/code/assert"
+)
+
+func TestExportTurtle(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+
+	db.Set("https://example.com/1", "name", "Hello")
+	db.Set("https://example.com/1", "category", "go", "sqlite")
+	db.Set("https://example.com/1", "author", Ref("john"))
+	db.Set("https://example.com/1", "http://schema.org/wordCount", 12)
+	db.Set("https://example.com/1", "http://www.w3.org/1999/02/22-rdf-syntax-ns#type", Ref("http://schema.org/BlogPosting"))
+	db.Set("https://example.com/1", "published", time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC))
+	db.Set("https://example.com/1", "draft", false)
+	db.Set("https://example.com/1", "rating", 4.5)
+	db.Set("john", "name", "John")
+
+	var buf bytes.Buffer
+	assert.Nil(db.ExportTurtle(&buf, About("https://example.com/1"), map[string]string{
+		"schema": "http://schema.org/",
+	}))
+
+	assert.Equal(`@prefix : <urn:numbersix:> .
+@prefix schema: <http://schema.org/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+
+<https://example.com/1> a schema:BlogPosting ;
+    :author _:john ;
+    :category "go", "sqlite" ;
+    :draft false ;
+    schema:wordCount 12 ;
+    :name "Hello" ;
+    :published "2019-01-02T03:04:05Z"^^xsd:dateTime ;
+    :rating 4.5 .
+`, buf.String())
+
+	t.Run("without prefixes", func(t *testing.T) {
+		var buf bytes.Buffer
+		groups, _ := db.Groups(About("john"))
+		assert.Nil(WriteTurtle(&buf, append(groups, groups...), map[string]string{"": "", "xsd": ""}))
+
+		assert.Equal(`_:john <urn:numbersix:name> "John" .
+
+_:john <urn:numbersix:name> "John" .
+`, buf.String())
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		other, _ := Open("file::memory:")
+
+		buf.Reset()
+		assert.Nil(db.ExportTurtle(&buf, All(), nil))
+		assert.Nil(other.ImportTurtle(&buf))
+
+		john, _ := other.List(Where("name", "John"))
+		if !assert.Len(john, 1) {
+			return
+		}
+		assert.True(strings.HasPrefix(john[0].Subject, "urn:uuid:"))
+
+		expected, _ := db.List(All())
+		actual, _ := other.List(All())
+		assert.Equal(replaceSubject(expected, "john", john[0].Subject), actual)
+	})
+}
+
+func TestImportTurtle(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+
+	err := db.ImportTurtle(strings.NewReader(`# a comment
+@base <https://example.com/> .
+@prefix : <urn:numbersix:> .
+PREFIX schema: <http://schema.org/>
+
+<post> a schema:BlogPosting ;
+  :name "Hello", 'Bonjour'@fr ;
+  :content """Line one
+Line "two"\n""" ;
+  :count 3 ; :ratio -0.5 ; :big 1E3 ;
+  :ok true ;
+  :when "2019-01-02T03:04:05Z"^^<http://www.w3.org/2001/XMLSchema#dateTime> ;
+  :author _:john ;
+  :syndication [ :url "https://example.org/1" ] ;
+  .
+
+_:john :name "John" .
+`))
+	assert.Nil(err)
+
+	groups, _ := db.Groups(About("https://example.com/post"))
+	if !assert.Len(groups, 1) {
+		return
+	}
+	properties := groups[0].Properties
+
+	syndication, _ := properties["syndication"][0].(Ref)
+	assert.True(strings.HasPrefix(string(syndication), "urn:uuid:"))
+	delete(properties, "syndication")
+
+	author, _ := properties["author"][0].(Ref)
+	assert.True(strings.HasPrefix(string(author), "urn:uuid:"))
+	delete(properties, "author")
+
+	assert.Equal(map[string][]interface{}{
+		"http://www.w3.org/1999/02/22-rdf-syntax-ns#type": {Ref("http://schema.org/BlogPosting")},
+		"name":    {"Bonjour", "Hello"},
+		"content": {"Line one\nLine \"two\"\n"},
+		"count":   {float64(3)},
+		"ratio":   {-0.5},
+		"big":     {float64(1000)},
+		"ok":      {true},
+		"when":    {"2019-01-02T03:04:05Z"},
+	}, properties)
+
+	ok, _ := db.Any(About(string(syndication)).Where("url", "https://example.org/1"))
+	assert.True(ok)
+
+	ok, _ = db.Any(About(string(author)).Where("name", "John"))
+	assert.True(ok)
+
+	ok, _ = db.Any(About("https://example.com/post").Where("big", 1000))
+	assert.True(ok)
+}
+
+func TestImportTurtleErrors(t *testing.T) {
+	testCases := map[string]string{
+		`<a:b> <a:c> "x"`:                  "line 1: unexpected end of document",
+		"<a:b>\n<a:c> ex:d .":              `line 2: undefined prefix "ex"`,
+		`<a:b> <a:c> ( 1 2 ) .`:            "line 1: collections are not supported",
+		`<a:b> _:c <a:d> .`:                "line 1: predicate must be an IRI",
+		`<a:b> <a:c> "x .`:                 "line 1: unterminated string",
+		`<a:b> <a:c> "x" <a:d> .`:          `line 1: unexpected "<a:d>"`,
+		`@prefix ex: <a:> . ex:b a ex:c .`: "",
+	}
+
+	for document, expected := range testCases {
+		t.Run(document, func(t *testing.T) {
+			db, _ := Open("file::memory:")
+
+			err := db.ImportTurtle(strings.NewReader(document))
+			if expected == "" {
+				assert.Nil(t, err)
+			} else if assert.NotNil(t, err) {
+				assert.Equal(t, expected, err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package numbersix
+
+import (
+	"context"
+	"database/sql"
+	"io"
+)
+
+// querier is satisfied by both *sql.DB and *sql.Tx, so that operations can be
+// shared between a DB and a Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// store performs operations on the named table, using either a DB or a Tx.
+type store struct {
+	ctx     context.Context
+	q       querier
+	name    string
+	flatten bool
+}
+
+// table is what queries are built against: the name of the table, and whether
+// the DB flattens objects so that predicates may be paths through them.
+type table struct {
+	name    string
+	flatten bool
+}
+
+func (s store) table() table {
+	return table{name: s.name, flatten: s.flatten}
+}
+
+// Tx is a transaction on a DB. All operations performed on a Tx will either be
+// committed together, or rolled back together.
+type Tx struct {
+	tx      *sql.Tx
+	name    string
+	ctx     context.Context
+	flatten bool
+}
+
+// Begin starts a transaction. The transaction must be finished by calling
+// Commit or Rollback. If the DB has a context, as set by WithContext, the
+// transaction is rolled back if the context is done before it is committed.
+func (d *DB) Begin() (*Tx, error) {
+	ctx := d.context()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tx{tx: tx, name: d.name, ctx: ctx, flatten: d.flatten}, nil
+}
+
+// Update runs fn within a transaction. If fn returns an error the transaction is
+// rolled back and the error returned, otherwise the transaction is committed. If
+// fn panics the transaction is rolled back before the panic continues.
+func (d *DB) Update(fn func(tx *Tx) error) error {
+	tx, err := d.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		terr := tx.Rollback()
+		if terr != nil {
+			return terr
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (t *Tx) store() store {
+	return store{ctx: t.ctx, q: t.tx, name: t.name, flatten: t.flatten}
+}
+
+// Commit the transaction.
+func (t *Tx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback aborts the transaction.
+func (t *Tx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// Set is the same as DB.Set, but runs within the transaction.
+func (t *Tx) Set(subject, predicate string, value interface{}, more ...interface{}) error {
+	if len(more) > 0 {
+		return t.SetMany(subject, predicate, append(more, value))
+	}
+
+	return t.store().set(subject, predicate, value)
+}
+
+// SetMany is the same as DB.SetMany, but runs within the transaction.
+func (t *Tx) SetMany(subject, predicate string, values interface{}) error {
+	return t.store().setMany(subject, predicate, values)
+}
+
+// SetProperties is the same as DB.SetProperties, but runs within the
+// transaction.
+func (t *Tx) SetProperties(subject string, properties map[string][]interface{}) error {
+	return t.store().setProperties(subject, properties)
+}
+
+// Replace is the same as DB.Replace, but runs within the transaction.
+func (t *Tx) Replace(subject, predicate string, values ...interface{}) error {
+	return t.ReplaceProperties(subject, map[string][]interface{}{
+		predicate: values,
+	})
+}
+
+// ReplaceProperties is the same as DB.ReplaceProperties, but runs within the
+// transaction.
+func (t *Tx) ReplaceProperties(subject string, properties map[string][]interface{}) error {
+	return t.store().replaceProperties(subject, properties)
+}
+
+// DeleteValue is the same as DB.DeleteValue, but runs within the transaction.
+func (t *Tx) DeleteValue(subject, predicate string, value interface{}) error {
+	return t.store().deleteValue(subject, predicate, value)
+}
+
+// DeletePredicate is the same as DB.DeletePredicate, but runs within the
+// transaction.
+func (t *Tx) DeletePredicate(subject, predicate string) error {
+	return t.store().deletePredicate(subject, predicate)
+}
+
+// DeleteSubject is the same as DB.DeleteSubject, but runs within the
+// transaction.
+func (t *Tx) DeleteSubject(subject string) error {
+	return t.store().deleteSubject(subject)
+}
+
+// List is the same as DB.List, but runs within the transaction.
+func (t *Tx) List(query Query) ([]Triple, error) {
+	return t.store().list(query)
+}
+
+// Any is the same as DB.Any, but runs within the transaction.
+func (t *Tx) Any(query AnyQuery) (bool, error) {
+	return t.store().exists(query)
+}
+
+// ListPage is the same as DB.ListPage, but runs within the transaction.
+func (t *Tx) ListPage(query PagedQuery) ([]Triple, *PageToken, error) {
+	return t.store().listPage(query)
+}
+
+// Iter is the same as DB.Iter, but runs within the transaction.
+func (t *Tx) Iter(query Query) (*Cursor, error) {
+	return t.store().iter(query)
+}
+
+// IterGrouped is the same as DB.IterGrouped, but runs within the transaction.
+func (t *Tx) IterGrouped(query Query) (*GroupCursor, error) {
+	return t.store().iterGrouped(query)
+}
+
+// Get is the same as DB.Get, but runs within the transaction.
+func (t *Tx) Get(subject string, v interface{}) error {
+	return get(t.List, subject, v)
+}
+
+// Put is the same as DB.Put, but runs within the transaction.
+func (t *Tx) Put(subject string, v interface{}, opts ...PutOption) error {
+	return t.store().put(subject, v, opts)
+}
+
+// ApplyUpdate is the same as DB.ApplyUpdate, but runs within the transaction.
+func (t *Tx) ApplyUpdate(subject string, update Update) error {
+	return t.store().applyUpdate(subject, update)
+}
+
+// ImportMF2 is the same as DB.ImportMF2, but runs within the transaction.
+func (t *Tx) ImportMF2(r io.Reader) ([]string, error) {
+	return t.store().importMF2(r)
+}
+
+// ExportMF2 is the same as DB.ExportMF2, but runs within the transaction.
+func (t *Tx) ExportMF2(subject string) (map[string]interface{}, error) {
+	return exportMF2(t.List, subject)
+}
+
+// Groups is the same as DB.Groups, but runs within the transaction.
+func (t *Tx) Groups(query Query) ([]Group, error) {
+	return t.store().groups(query)
+}
+
+// Referrers is the same as DB.Referrers, but runs within the transaction.
+func (t *Tx) Referrers(subject string) ([]Triple, error) {
+	return t.store().list(referrersQuery{subject: subject})
+}
+
+// ExportNTriples is the same as DB.ExportNTriples, but runs within the
+// transaction.
+func (t *Tx) ExportNTriples(w io.Writer, query Query) error {
+	return t.store().exportNQuads(w, query, "")
+}
+
+// ExportNQuads is the same as DB.ExportNQuads, but runs within the transaction.
+func (t *Tx) ExportNQuads(w io.Writer, query Query, graph string) error {
+	return t.store().exportNQuads(w, query, graph)
+}
+
+// ImportNTriples is the same as DB.ImportNTriples, but runs within the
+// transaction, so all of the triples are stored or none are.
+func (t *Tx) ImportNTriples(r io.Reader) error {
+	return t.store().importNQuads(newQuadReader(r, false), "")
+}
+
+// ImportNQuads is the same as DB.ImportNQuads, but runs within the transaction,
+// so all of the triples are stored or none are.
+func (t *Tx) ImportNQuads(r io.Reader, graph string) error {
+	return t.store().importNQuads(newQuadReader(r, true), graph)
+}
+
+// ExportTurtle is the same as DB.ExportTurtle, but runs within the transaction.
+func (t *Tx) ExportTurtle(w io.Writer, query Query, prefixes map[string]string) error {
+	return t.store().exportTurtle(w, query, prefixes)
+}
+
+// ImportTurtle is the same as DB.ImportTurtle, but runs within the transaction.
+func (t *Tx) ImportTurtle(r io.Reader) error {
+	return t.store().importTurtle(r)
+}
+
+// Dump is the same as DB.Dump, but runs within the transaction.
+func (t *Tx) Dump(w io.Writer) error {
+	return t.store().dump(w)
+}
+
+// Restore is the same as DB.Restore, but runs within the transaction, so all of
+// the triples are stored or none are. The BatchSize option is not used.
+func (t *Tx) Restore(r io.Reader, opts RestoreOptions) error {
+	return t.store().restore(r, opts.Mode)
+}
@@ -0,0 +1,137 @@
+package numbersix
+
+import (
+	"errors"
+	"testing"
+
+	"hawx.me/code/assert"
+)
+
+func TestUpdate(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	assert.Nil(db.Set("post", "content", "old"))
+	assert.Nil(db.Set("post", "updated", 1))
+
+	err := db.Update(func(tx *Tx) error {
+		if err := tx.DeletePredicate("post", "content"); err != nil {
+			return err
+		}
+		if err := tx.Set("post", "content", "new"); err != nil {
+			return err
+		}
+		if err := tx.DeleteValue("post", "updated", 1); err != nil {
+			return err
+		}
+		return tx.Set("post", "updated", 2)
+	})
+	assert.Nil(err)
+
+	triples, err := db.List(About("post"))
+	assert.Nil(err)
+
+	if assert.Len(triples, 2) {
+		var content string
+		assert.Equal("content", triples[0].Predicate)
+		assert.Nil(triples[0].Value(&content))
+		assert.Equal("new", content)
+
+		var updated int
+		assert.Equal("updated", triples[1].Predicate)
+		assert.Nil(triples[1].Value(&updated))
+		assert.Equal(2, updated)
+	}
+}
+
+func TestUpdateWithError(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	assert.Nil(db.Set("post", "content", "old"))
+
+	updateErr := errors.New("failed")
+
+	err := db.Update(func(tx *Tx) error {
+		if err := tx.DeleteSubject("post"); err != nil {
+			return err
+		}
+		if err := tx.SetProperties("post", map[string][]interface{}{
+			"content": {"new"},
+		}); err != nil {
+			return err
+		}
+
+		triples, err := tx.List(About("post"))
+		assert.Nil(err)
+		assert.Len(triples, 1)
+
+		return updateErr
+	})
+	assert.Equal(updateErr, err)
+
+	triples, err := db.List(About("post"))
+	assert.Nil(err)
+
+	if assert.Len(triples, 1) {
+		var content string
+		assert.Nil(triples[0].Value(&content))
+		assert.Equal("old", content)
+	}
+}
+
+func TestUpdateWithPanic(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	assert.Nil(db.Set("post", "content", "old"))
+
+	func() {
+		defer func() {
+			assert.Equal("failed", recover())
+		}()
+
+		db.Update(func(tx *Tx) error {
+			if err := tx.Set("post", "content", "new"); err != nil {
+				return err
+			}
+
+			panic("failed")
+		})
+	}()
+
+	triples, err := db.List(About("post"))
+	assert.Nil(err)
+	assert.Len(triples, 1)
+
+	assert.Nil(db.Set("post", "content", "newer"))
+}
+
+func TestBegin(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+
+	tx, err := db.Begin()
+	assert.Nil(err)
+	assert.Nil(tx.SetMany("thing", "tag", []string{"a", "b"}))
+
+	ok, err := tx.Any(About("thing"))
+	assert.Nil(err)
+	assert.True(ok)
+
+	assert.Nil(tx.Rollback())
+
+	ok, err = db.Any(About("thing"))
+	assert.Nil(err)
+	assert.False(ok)
+
+	tx, err = db.Begin()
+	assert.Nil(err)
+	assert.Nil(tx.SetMany("thing", "tag", []string{"a", "b"}))
+	assert.Nil(tx.Commit())
+
+	ok, err = db.Any(About("thing"))
+	assert.Nil(err)
+	assert.True(ok)
+}
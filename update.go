@@ -0,0 +1,100 @@
+package numbersix
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// An Update describes changes to make to the properties of a subject, in the
+// same shape as a Micropub update request. For example:
+//
+//    {
+//      "replace": {"content": ["hello moon"]},
+//      "add": {"category": ["moon"]},
+//      "delete": ["syndication"]
+//    }
+//
+// Can be unmarshaled in to an Update and applied with ApplyUpdate.
+type Update struct {
+	// Replace lists predicates to replace all values of.
+	Replace map[string][]interface{} `json:"replace,omitempty"`
+
+	// Add lists values to add to predicates.
+	Add map[string][]interface{} `json:"add,omitempty"`
+
+	// Delete lists predicates, or values of predicates, to remove.
+	Delete *Deletion `json:"delete,omitempty"`
+}
+
+// A Deletion lists predicates to remove all values of, and values to remove
+// from predicates. It can be unmarshaled from either a list of predicates, or an
+// object of predicates to lists of values.
+type Deletion struct {
+	Predicates []string
+	Values     map[string][]interface{}
+}
+
+// UnmarshalJSON reads either a list of predicates or an object of values.
+func (d *Deletion) UnmarshalJSON(data []byte) error {
+	var predicates []string
+	if err := json.Unmarshal(data, &predicates); err == nil {
+		d.Predicates = predicates
+		return nil
+	}
+
+	var values map[string][]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return errors.New("delete must be a list of properties or an object of values")
+	}
+	d.Values = values
+
+	return nil
+}
+
+// MarshalJSON writes the predicates if there are any, otherwise the values.
+func (d Deletion) MarshalJSON() ([]byte, error) {
+	if len(d.Predicates) > 0 || d.Values == nil {
+		return json.Marshal(d.Predicates)
+	}
+
+	return json.Marshal(d.Values)
+}
+
+// ApplyUpdate makes the changes described by the update to the subject's
+// values. Replacements are made first, then additions, then deletions. All
+// changes are made in a single transaction, so if any fail none are made.
+func (d *DB) ApplyUpdate(subject string, update Update) error {
+	return d.Update(func(tx *Tx) error {
+		return tx.ApplyUpdate(subject, update)
+	})
+}
+
+func (s store) applyUpdate(subject string, update Update) error {
+	if err := s.replaceProperties(subject, update.Replace); err != nil {
+		return err
+	}
+
+	if err := s.setProperties(subject, update.Add); err != nil {
+		return err
+	}
+
+	if update.Delete == nil {
+		return nil
+	}
+
+	for _, predicate := range update.Delete.Predicates {
+		if err := s.deletePredicate(subject, predicate); err != nil {
+			return err
+		}
+	}
+
+	for predicate, values := range update.Delete.Values {
+		for _, value := range values {
+			if err := s.deleteValue(subject, predicate, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
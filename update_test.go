@@ -0,0 +1,86 @@
+package numbersix
+
+import (
+	"encoding/json"
+	"testing"
+
+	"hawx.me/code/assert"
+)
+
+func TestApplyUpdate(t *testing.T) {
+	assert := assert.New(t)
+
+	db, _ := Open("file::memory:")
+	assert.Nil(db.SetProperties("post", map[string][]interface{}{
+		"content":     {"hello world"},
+		"category":    {"foo", "bar"},
+		"syndication": {"http://web.archive.org/web/20040104110725/https://aaronpk.example/2014/06/01/9/indieweb"},
+	}))
+
+	var update Update
+	assert.Nil(json.Unmarshal([]byte(`{
+  "action": "update",
+  "url": "https://example.com/post",
+  "replace": {
+    "content": ["hello moon"]
+  },
+  "add": {
+    "category": ["moon"]
+  },
+  "delete": ["syndication"]
+}`), &update))
+
+	assert.Nil(db.ApplyUpdate("post", update))
+
+	triples, err := db.List(About("post"))
+	assert.Nil(err)
+	properties, err := triplesToMap(triples)
+	assert.Nil(err)
+
+	assert.Equal(map[string][]interface{}{
+		"content":  {"hello moon"},
+		"category": {"bar", "foo", "moon"},
+	}, properties)
+
+	update = Update{}
+	assert.Nil(json.Unmarshal([]byte(`{
+  "action": "update",
+  "url": "https://example.com/post",
+  "delete": {
+    "category": ["foo", "moon"]
+  }
+}`), &update))
+
+	assert.Nil(db.ApplyUpdate("post", update))
+
+	triples, err = db.List(About("post"))
+	assert.Nil(err)
+	properties, err = triplesToMap(triples)
+	assert.Nil(err)
+
+	assert.Equal(map[string][]interface{}{
+		"content":  {"hello moon"},
+		"category": {"bar"},
+	}, properties)
+}
+
+func TestUpdateUnmarshalInvalidDelete(t *testing.T) {
+	var update Update
+	assert.NotNil(t, json.Unmarshal([]byte(`{"delete": "category"}`), &update))
+}
+
+func TestUpdateMarshal(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := json.Marshal(Update{Delete: &Deletion{Predicates: []string{"category"}}})
+	assert.Nil(err)
+	assert.Equal(`{"delete":["category"]}`, string(data))
+
+	data, err = json.Marshal(Update{Delete: &Deletion{Values: map[string][]interface{}{"category": {"foo"}}}})
+	assert.Nil(err)
+	assert.Equal(`{"delete":{"category":["foo"]}}`, string(data))
+
+	data, err = json.Marshal(Update{Add: map[string][]interface{}{"category": {"foo"}}})
+	assert.Nil(err)
+	assert.Equal(`{"add":{"category":["foo"]}}`, string(data))
+}